@@ -0,0 +1,397 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/log"
+	"tools-admin/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var alertService = &service.AlertService{}
+
+// GetAlertRules 获取告警规则，参数taskId不为空时返回对该任务生效的规则
+func GetAlertRules(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	taskID, _ := strconv.ParseUint(c.DefaultQuery("taskId", "0"), 10, 32)
+	rules, err := alertService.ListRules(uint(taskID))
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取告警规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取告警规则成功",
+		"data": gin.H{
+			"list":      rules,
+			"ruleTypes": model.AlertRuleTypeMap,
+		},
+	})
+}
+
+// CreateAlertRule 创建告警规则
+func CreateAlertRule(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	var rule model.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的请求参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rule.ID = 0
+
+	if err := alertService.CreateRule(&rule); err != nil {
+		log.Error(fmt.Sprintf("创建告警规则失败: %v", err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "创建告警规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "创建告警规则成功",
+		"data":    rule,
+	})
+}
+
+// UpdateAlertRule 更新告警规则
+func UpdateAlertRule(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的规则ID",
+		})
+		return
+	}
+
+	rule, err := alertService.GetRule(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "告警规则不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var updates model.AlertRule
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的请求参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rule.Name = updates.Name
+	rule.TaskID = updates.TaskID
+	rule.Type = updates.Type
+	rule.Threshold = updates.Threshold
+	rule.ChannelIDs = updates.ChannelIDs
+	rule.Interval = updates.Interval
+	rule.Disabled = updates.Disabled
+	rule.Description = updates.Description
+
+	if err := alertService.UpdateRule(rule); err != nil {
+		log.Error(fmt.Sprintf("更新告警规则失败, ID: %d, 错误: %v", id, err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "更新告警规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "更新告警规则成功",
+		"data":    rule,
+	})
+}
+
+// DeleteAlertRule 删除告警规则
+func DeleteAlertRule(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的规则ID",
+		})
+		return
+	}
+
+	if err := alertService.DeleteRule(uint(id)); err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "删除告警规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "删除告警规则成功",
+	})
+}
+
+// GetAlertChannels 获取告警通道
+func GetAlertChannels(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	channels, err := alertService.ListChannels()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取告警通道失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	for _, channel := range channels {
+		alertService.MaskChannel(channel)
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取告警通道成功",
+		"data": gin.H{
+			"list":         channels,
+			"channelTypes": model.AlertChannelTypeMap,
+		},
+	})
+}
+
+// CreateAlertChannel 创建告警通道
+func CreateAlertChannel(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	var channel model.AlertChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的请求参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+	channel.ID = 0
+
+	if err := alertService.CreateChannel(&channel); err != nil {
+		log.Error(fmt.Sprintf("创建告警通道失败: %v", err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "创建告警通道失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	alertService.MaskChannel(&channel)
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "创建告警通道成功",
+		"data":    channel,
+	})
+}
+
+// UpdateAlertChannel 更新告警通道
+func UpdateAlertChannel(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的通道ID",
+		})
+		return
+	}
+
+	channel, err := alertService.GetChannel(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "告警通道不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var updates model.AlertChannel
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的请求参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+	channel.Name = updates.Name
+	channel.Type = updates.Type
+	channel.Config = updates.Config
+	channel.Disabled = updates.Disabled
+
+	if err := alertService.UpdateChannel(channel); err != nil {
+		log.Error(fmt.Sprintf("更新告警通道失败, ID: %d, 错误: %v", id, err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "更新告警通道失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	alertService.MaskChannel(channel)
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "更新告警通道成功",
+		"data":    channel,
+	})
+}
+
+// DeleteAlertChannel 删除告警通道
+func DeleteAlertChannel(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的通道ID",
+		})
+		return
+	}
+
+	if err := alertService.DeleteChannel(uint(id)); err != nil {
+		log.Error(fmt.Sprintf("删除告警通道失败, ID: %d, 错误: %v", id, err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "删除告警通道失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "删除告警通道成功",
+	})
+}
+
+// TestAlertChannel 通过告警通道发送测试消息
+func TestAlertChannel(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的通道ID",
+		})
+		return
+	}
+
+	if err := alertService.TestChannel(uint(id)); err != nil {
+		log.Error(fmt.Sprintf("告警通道测试失败, ID: %d, 错误: %v", id, err))
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "发送测试消息失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "发送测试消息成功",
+	})
+}
+
+// GetAlertEvents 分页获取告警事件，可按任务和状态过滤
+func GetAlertEvents(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	taskID, _ := strconv.ParseUint(c.DefaultQuery("taskId", "0"), 10, 32)
+
+	events, total, err := alertService.ListEvents(page, pageSize, uint(taskID), c.Query("status"))
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取告警事件失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取告警事件成功",
+		"data": gin.H{
+			"list":  events,
+			"total": total,
+		},
+	})
+}
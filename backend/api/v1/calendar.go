@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/log"
+	"tools-admin/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var calendarService = &service.CalendarService{}
+
+// GetCalendars 获取日历列表
+func GetCalendars(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	calendars, err := calendarService.List()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取日历列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取日历列表成功",
+		"data": gin.H{
+			"list":      calendars,
+			"ruleTypes": model.CalendarRuleTypeMap,
+		},
+	})
+}
+
+// GetCalendar 获取日历详情
+func GetCalendar(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的日历ID",
+		})
+		return
+	}
+
+	calendar, err := calendarService.Get(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "日历不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取日历详情成功",
+		"data":    calendar,
+	})
+}
+
+// CreateCalendar 创建日历
+func CreateCalendar(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	var calendar model.Calendar
+	if err := c.ShouldBindJSON(&calendar); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的请求参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+	calendar.ID = 0
+	for i := range calendar.Rules {
+		calendar.Rules[i].ID = 0
+	}
+
+	if err := calendarService.Create(&calendar); err != nil {
+		log.Error(fmt.Sprintf("创建日历失败: %v", err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "创建日历失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "创建日历成功",
+		"data":    calendar,
+	})
+}
+
+// UpdateCalendar 更新日历，请求中的规则会整体替换原有规则
+func UpdateCalendar(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的日历ID",
+		})
+		return
+	}
+
+	if _, err := calendarService.Get(uint(id)); err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "日历不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var calendar model.Calendar
+	if err := c.ShouldBindJSON(&calendar); err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的请求参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+	calendar.ID = uint(id)
+
+	if err := calendarService.Update(&calendar); err != nil {
+		log.Error(fmt.Sprintf("更新日历失败, ID: %d, 错误: %v", id, err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "更新日历失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "更新日历成功",
+		"data":    calendar,
+	})
+}
+
+// DeleteCalendar 删除日历
+func DeleteCalendar(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的日历ID",
+		})
+		return
+	}
+
+	if err := calendarService.Delete(uint(id)); err != nil {
+		log.Error(fmt.Sprintf("删除日历失败, ID: %d, 错误: %v", id, err))
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "删除日历失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "删除日历成功",
+	})
+}
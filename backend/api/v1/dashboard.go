@@ -2,6 +2,8 @@ package v1
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"tools-admin/backend/service"
@@ -44,11 +46,15 @@ func (api *DashboardApi) GetOverview(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param period query string true "时间周期(week/month)"
+// @Param taskId query int false "任务ID"
+// @Param type query int false "任务类型"
 // @Success 200 {array} model.ChartData
 // @Router /api/v1/dashboard/task-chart [get]
 func (api *DashboardApi) GetTaskChart(c *gin.Context) {
 	period := c.DefaultQuery("period", "week")
-	data, code := api.dashboardService.GetTaskChart(period)
+	taskID, _ := strconv.ParseUint(c.DefaultQuery("taskId", "0"), 10, 32)
+	taskType, _ := strconv.Atoi(c.DefaultQuery("type", "0"))
+	data, code := api.dashboardService.GetTaskChart(period, uint(taskID), taskType)
 	if code != 0 {
 		c.JSON(http.StatusOK, gin.H{
 			"code": code,
@@ -65,6 +71,87 @@ func (api *DashboardApi) GetTaskChart(c *gin.Context) {
 	})
 }
 
+// GetTaskBreakdown 获取按任务或类型汇总的任务统计
+// @Summary 获取按任务或类型汇总的任务统计
+// @Description 获取周期内每个任务或每种任务类型的执行次数和成功率
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param period query string true "时间周期(week/month)"
+// @Param by query string false "汇总维度(task/type)，默认task"
+// @Success 200 {array} model.TaskStatisticsBreakdown
+// @Router /api/v1/dashboard/task-breakdown [get]
+func (api *DashboardApi) GetTaskBreakdown(c *gin.Context) {
+	period := c.DefaultQuery("period", "week")
+	by := c.DefaultQuery("by", "task")
+	data, code := api.dashboardService.GetTaskBreakdown(period, by)
+	if code != 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  "获取任务统计明细失败",
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// RebuildTaskStatistics 根据执行日志重建日期范围内的任务统计
+// @Summary 重建任务统计
+// @Description 根据执行日志重新计算日期范围内(含首尾)的每日统计，范围内日志已归档清理时需指定force
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param body body object true "{from: 2006-01-02, to: 2006-01-02, force: false}"
+// @Success 200 {object} model.TaskStatisticsRebuildResult
+// @Router /api/v1/dashboard/task-statistics/rebuild [post]
+func (api *DashboardApi) RebuildTaskStatistics(c *gin.Context) {
+	var req struct {
+		From  string `json:"from" binding:"required"`
+		To    string `json:"to" binding:"required"`
+		Force bool   `json:"force"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误",
+			"data": nil,
+		})
+		return
+	}
+	from, err1 := time.ParseInLocation("2006-01-02", req.From, time.Local)
+	to, err2 := time.ParseInLocation("2006-01-02", req.To, time.Local)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 400,
+			"msg":  "日期格式应为 2006-01-02",
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.TaskStats.Rebuild(from, to, req.Force)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 1,
+			"msg":  "重建任务统计失败: " + err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
 // GetSmsChart 获取短信图表数据
 // @Summary 获取短信图表数据
 // @Description 获取短信发送成功率趋势数据
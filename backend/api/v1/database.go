@@ -42,6 +42,25 @@ func GetDatabases(c *gin.Context) {
 	})
 }
 
+// GetDatabase 获取数据库连接详情
+func GetDatabase(c *gin.Context) {
+	resp, err := dbService.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    404,
+			"message": "数据库连接不存在",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取数据库连接成功",
+		"data":    resp,
+	})
+}
+
 // CreateDatabase 创建数据库连接
 func CreateDatabase(c *gin.Context) {
 	var req model.DatabaseCreateReq
@@ -110,6 +129,22 @@ func ExecuteQuery(c *gin.Context) {
 		return
 	}
 
+	// 导出模式：结果以文件流式返回
+	if req.Export != "" {
+		if err := dbService.ExportQuery(&req, c); err != nil && !c.Writer.Written() {
+			// 尚未写出文件内容，清除导出文件的响应头后返回错误
+			for _, key := range []string{"Content-Type", "Content-Disposition", "Trailer"} {
+				c.Writer.Header().Del(key)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"code":    500,
+				"message": "导出查询结果失败",
+				"data":    err.Error(),
+			})
+		}
+		return
+	}
+
 	resp, err := dbService.ExecuteQuery(&req, c)
 	if err != nil {
 		log.Error("执行SQL查询失败: %v", err)
@@ -128,6 +163,54 @@ func ExecuteQuery(c *gin.Context) {
 	})
 }
 
+// CancelQuery 取消执行中的SQL查询
+func CancelQuery(c *gin.Context) {
+	queryID := c.Param("queryId")
+	if err := dbService.CancelQuery(queryID, c); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "取消SQL查询失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已取消SQL查询",
+	})
+}
+
+// AnalyzeSQL 分析SQL语句的类型、涉及的表和安全风险
+func AnalyzeSQL(c *gin.Context) {
+	var req model.SQLAnalyzeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	result, err := dbService.AnalyzeSQL(&req)
+	if err != nil {
+		log.Error("分析SQL失败: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "分析SQL失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "分析SQL成功",
+		"data":    result,
+	})
+}
+
 // GetTables 获取数据库表列表
 func GetTables(c *gin.Context) {
 	var req model.TableListReq
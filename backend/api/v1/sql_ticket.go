@@ -0,0 +1,214 @@
+package v1
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ticketID 解析路径中的工单ID
+func ticketID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    400,
+			"message": "无效的工单ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetSQLTickets 获取SQL变更工单列表
+func GetSQLTickets(c *gin.Context) {
+	var req model.SQLTicketListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	resp, err := dbService.ListTickets(&req, c)
+	if err != nil {
+		log.Error("获取SQL变更工单列表失败: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "获取SQL变更工单列表失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取SQL变更工单列表成功",
+		"data":    resp,
+	})
+}
+
+// GetSQLTicket 获取SQL变更工单详情，包含分析结果和审计记录
+func GetSQLTicket(c *gin.Context) {
+	id, ok := ticketID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := dbService.GetTicket(id)
+	if err != nil {
+		log.Error("获取SQL变更工单失败: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "获取SQL变更工单失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取SQL变更工单成功",
+		"data":    detail,
+	})
+}
+
+// CreateSQLTicket 提交SQL变更工单
+func CreateSQLTicket(c *gin.Context) {
+	var req model.SQLTicketCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	ticket, err := dbService.SubmitTicket(&req, c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "提交SQL变更工单失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "提交SQL变更工单成功",
+		"data":    ticket,
+	})
+}
+
+// ApproveSQLTicket 审批通过SQL变更工单
+func ApproveSQLTicket(c *gin.Context) {
+	reviewSQLTicket(c, true)
+}
+
+// RejectSQLTicket 驳回SQL变更工单
+func RejectSQLTicket(c *gin.Context) {
+	reviewSQLTicket(c, false)
+}
+
+func reviewSQLTicket(c *gin.Context, approve bool) {
+	id, ok := ticketID(c)
+	if !ok {
+		return
+	}
+	// 审批意见可以不填，允许空请求体
+	var req model.SQLTicketReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	action := "驳回SQL变更工单"
+	if approve {
+		action = "审批SQL变更工单"
+	}
+	ticket, err := dbService.ReviewTicket(id, approve, &req, c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": action + "失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": action + "成功",
+		"data":    ticket,
+	})
+}
+
+// CancelSQLTicket 撤销SQL变更工单
+func CancelSQLTicket(c *gin.Context) {
+	id, ok := ticketID(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := dbService.CancelTicket(id, c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "撤销SQL变更工单失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "撤销SQL变更工单成功",
+		"data":    ticket,
+	})
+}
+
+// ExecuteSQLTicket 执行已审批的SQL变更工单。执行失败时工单状态为failed，
+// 返回的工单中包含错误信息和实际影响行数
+func ExecuteSQLTicket(c *gin.Context) {
+	id, ok := ticketID(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := dbService.ExecuteTicket(id, c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "执行SQL变更工单失败",
+			"data":    err.Error(),
+		})
+		return
+	}
+	if ticket.Status == model.TicketFailed {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    500,
+			"message": "执行SQL变更工单失败: " + ticket.Error,
+			"data":    ticket,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "执行SQL变更工单成功",
+		"data":    ticket,
+	})
+}
@@ -1,8 +1,13 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 	"tools-admin/backend/model"
 	"tools-admin/backend/pkg/cronutil"
 	"tools-admin/backend/pkg/log"
@@ -13,6 +18,14 @@ import (
 
 var taskService = &service.TaskService{}
 
+// currentOperator 返回当前登录用户
+func currentOperator(c *gin.Context) model.Operator {
+	return model.Operator{
+		UserID:   c.GetUint("user_id"),
+		Username: c.GetString("username"),
+	}
+}
+
 // GetTasks 获取任务列表
 func GetTasks(c *gin.Context) {
 	// 如果是OPTIONS请求，直接返回
@@ -54,11 +67,30 @@ func CreateTask(c *gin.Context) {
 		Name        string          `json:"name"`
 		Type        interface{}     `json:"type"`
 		Description string          `json:"description"`
+		Key         string          `json:"key"`
 		Status      interface{}     `json:"status"`
 		Priority    string          `json:"priority"`
 		CronExpr    string          `json:"cronExpr"`
+		Timezone    string          `json:"timezone"`
+		CalendarID  uint            `json:"calendarId"`
 		TaskContent string          `json:"taskContent"`
 		TaskParams  string          `json:"taskParams"`
+
+		RetryMaxAttempts int    `json:"retryMaxAttempts"`
+		RetryBackoff     string `json:"retryBackoff"`
+		RetryInterval    int    `json:"retryInterval"`
+		RetryOn          string `json:"retryOn"`
+
+		UpstreamIDs model.IDList `json:"upstreamIds"`
+		TriggerRule string       `json:"triggerRule"`
+
+		ConcurrencyPolicy string `json:"concurrencyPolicy"`
+
+		MisfirePolicy string `json:"misfirePolicy"`
+		MisfireLimit  int    `json:"misfireLimit"`
+
+		LogRetentionDays int `json:"logRetentionDays"`
+		LogRetentionRuns int `json:"logRetentionRuns"`
 	}
 
 	if err := c.ShouldBindJSON(&requestData); err != nil {
@@ -72,14 +104,33 @@ func CreateTask(c *gin.Context) {
 
 	// 创建任务对象
 	task := &model.Task{
+		Key:         requestData.Key,
 		Name:        requestData.Name,
 		Description: requestData.Description,
 		Priority:    requestData.Priority,
 		CronExpr:    requestData.CronExpr,
+		Timezone:    requestData.Timezone,
+		CalendarID:  requestData.CalendarID,
 		TaskContent: requestData.TaskContent,
 		TaskParams:  requestData.TaskParams,
 		Status:      model.TaskStatusStopped,  // 默认为停止状态
 		ExecStatus:  model.TaskExecStatusPending,  // 默认为待执行状态
+
+		RetryMaxAttempts: requestData.RetryMaxAttempts,
+		RetryBackoff:     requestData.RetryBackoff,
+		RetryInterval:    requestData.RetryInterval,
+		RetryOn:          requestData.RetryOn,
+
+		UpstreamIDs: requestData.UpstreamIDs,
+		TriggerRule: requestData.TriggerRule,
+
+		ConcurrencyPolicy: requestData.ConcurrencyPolicy,
+
+		MisfirePolicy: requestData.MisfirePolicy,
+		MisfireLimit:  requestData.MisfireLimit,
+
+		LogRetentionDays: requestData.LogRetentionDays,
+		LogRetentionRuns: requestData.LogRetentionRuns,
 	}
 
 	// 处理类型字段
@@ -87,17 +138,8 @@ func CreateTask(c *gin.Context) {
 	case float64:
 		task.Type = model.TaskType(int8(v))
 	case string:
-		typeMap := map[string]int8{
-			"shell":     1,
-			"http":      2,
-			"datax":     3,
-			"regular":   4,
-			"urgent":    5,
-			"longterm":  6,
-			"recurring": 7,
-		}
-		if typeNum, exists := typeMap[v]; exists {
-			task.Type = model.TaskType(typeNum)
+		if taskType, exists := model.ParseTaskType(v); exists {
+			task.Type = taskType
 		} else {
 			task.Type = model.TaskTypeShell // 默认为shell类型
 		}
@@ -134,11 +176,15 @@ func CreateTask(c *gin.Context) {
 	}
 
 	// 创建任务
-	err := taskService.Create(task)
+	err := taskService.Create(task, currentOperator(c))
 	if err != nil {
 		log.Error(fmt.Sprintf("创建任务失败: %v", err))
-		c.JSON(500, gin.H{
-			"code":    500,
+		code := 500
+		if errors.Is(err, service.ErrInvalidTask) {
+			code = 400
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
 			"message": "创建任务失败",
 			"error":   err.Error(),
 		})
@@ -191,20 +237,40 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// 更新任务字段
+	// 更新任务字段，未传标识时保留原标识
+	if updates.Key != "" {
+		task.Key = updates.Key
+	}
 	task.Name = updates.Name
 	task.Description = updates.Description
 	task.Type = updates.Type
 	task.Priority = updates.Priority
 	task.Status = updates.Status
 	task.CronExpr = updates.CronExpr
+	task.Timezone = updates.Timezone
+	task.CalendarID = updates.CalendarID
 	task.TaskContent = updates.TaskContent
 	task.TaskParams = updates.TaskParams
+	task.RetryMaxAttempts = updates.RetryMaxAttempts
+	task.RetryBackoff = updates.RetryBackoff
+	task.RetryInterval = updates.RetryInterval
+	task.RetryOn = updates.RetryOn
+	task.UpstreamIDs = updates.UpstreamIDs
+	task.TriggerRule = updates.TriggerRule
+	task.ConcurrencyPolicy = updates.ConcurrencyPolicy
+	task.MisfirePolicy = updates.MisfirePolicy
+	task.MisfireLimit = updates.MisfireLimit
+	task.LogRetentionDays = updates.LogRetentionDays
+	task.LogRetentionRuns = updates.LogRetentionRuns
 
 	// 保存更新
-	if err := taskService.Update(task); err != nil {
-		c.JSON(500, gin.H{
-			"code":    500,
+	if err := taskService.Update(task, currentOperator(c)); err != nil {
+		code := 500
+		if errors.Is(err, service.ErrInvalidTask) {
+			code = 400
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
 			"message": "更新任务失败",
 			"error":   err.Error(),
 		})
@@ -296,7 +362,17 @@ func GetTaskLogs(c *gin.Context) {
 		return
 	}
 
-	logs, err := taskService.GetLogs(uint(id))
+	query, err := parseTaskLogQuery(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的查询参数",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	logs, total, err := taskService.GetLogs(uint(id), query)
 	if err != nil {
 		log.Error(fmt.Sprintf("获取任务日志失败, ID: %d, 错误: %v", id, err))
 		c.JSON(500, gin.H{
@@ -310,7 +386,190 @@ func GetTaskLogs(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code":    0,
 		"message": "获取任务日志成功",
-		"data":    logs,
+		"data": gin.H{
+			"list":  logs,
+			"total": total,
+		},
+	})
+}
+
+// parseTaskLogQuery 解析日志查询参数。status 可为状态名称或数值；startTime、endTime
+// 支持日期、日期时间和RFC3339格式，endTime只有日期时包含当天
+func parseTaskLogQuery(c *gin.Context) (*model.TaskLogQuery, error) {
+	query := &model.TaskLogQuery{
+		RunID:   c.Query("runId"),
+		Keyword: strings.TrimSpace(c.Query("keyword")),
+	}
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.PageSize, _ = strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 || query.PageSize > 500 {
+		query.PageSize = 10
+	}
+
+	if status := c.Query("status"); status != "" {
+		if s, ok := model.ParseTaskExecStatus(status); ok {
+			query.Status = s
+		} else if n, err := strconv.Atoi(status); err == nil && model.TaskExecStatusMap[model.TaskExecStatus(n)] != "" {
+			query.Status = model.TaskExecStatus(n)
+		} else {
+			return nil, fmt.Errorf("无效的执行状态: %s", status)
+		}
+	}
+
+	if value := c.Query("startTime"); value != "" {
+		t, _, err := parseQueryTime(value)
+		if err != nil {
+			return nil, err
+		}
+		query.StartFrom = &t
+	}
+	if value := c.Query("endTime"); value != "" {
+		t, dateOnly, err := parseQueryTime(value)
+		if err != nil {
+			return nil, err
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		query.StartTo = &t
+	}
+	return query, nil
+}
+
+// parseQueryTime 按服务器时区解析时间参数，dateOnly表示只有日期
+func parseQueryTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339} {
+		if t, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("无效的时间: %s", value)
+}
+
+// GetTaskLogArchives 获取任务的日志归档文件
+func GetTaskLogArchives(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	archives, err := service.LogRetention.ListArchives(uint(id))
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取日志归档失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取日志归档成功",
+		"data":    archives,
+	})
+}
+
+// DownloadTaskLogArchive 下载任务日志归档文件
+func DownloadTaskLogArchive(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+	archiveID, err := strconv.ParseUint(c.Param("archiveId"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的归档ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	archive, path, err := service.LogRetention.ArchivePath(uint(id), uint(archiveID))
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "归档文件不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.FileAttachment(path, filepath.Base(archive.FileName))
+}
+
+// PurgeTaskLogs 立即按保留策略清理任务日志
+func PurgeTaskLogs(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	task, err := taskService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "任务不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result, err := service.LogRetention.Purge(task)
+	if err != nil {
+		log.Error(fmt.Sprintf("清理任务日志失败, ID: %d, 错误: %v", id, err))
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "清理任务日志失败",
+			"error":   err.Error(),
+			"data":    result,
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "清理任务日志成功",
+		"data":    result,
 	})
 }
 
@@ -333,10 +592,33 @@ func RunTask(c *gin.Context) {
 		return
 	}
 
-	if err := taskService.RunTask(uint(id)); err != nil {
+	// 请求体可选，格式为 {"params": {...}}
+	var req struct {
+		Params map[string]interface{} `json:"params"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error(fmt.Sprintf("运行任务参数错误: %v", err))
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": "无效的运行参数",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	runID, err := taskService.RunTask(uint(id), req.Params)
+	if err != nil {
 		log.Error(fmt.Sprintf("运行任务失败, ID: %d, 错误: %v", id, err))
-		c.JSON(500, gin.H{
-			"code":    500,
+		code := 500
+		if errors.Is(err, service.ErrTaskRunning) {
+			code = 409
+		} else if errors.Is(err, service.ErrRenderFailed) {
+			code = 400
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
 			"message": "运行任务失败",
 			"error":   err.Error(),
 		})
@@ -346,6 +628,144 @@ func RunTask(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code":    0,
 		"message": "任务运行成功",
+		"data":    gin.H{"runId": runID},
+	})
+}
+
+// GetTaskRuns 获取任务正在进行的执行
+func GetTaskRuns(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		log.Error(fmt.Sprintf("获取任务执行ID参数错误: %v", err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取任务执行成功",
+		"data":    taskService.ListRuns(uint(id)),
+	})
+}
+
+// CancelTaskRun 取消任务的一次执行
+func CancelTaskRun(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		log.Error(fmt.Sprintf("取消任务执行ID参数错误: %v", err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+	runID := c.Param("runId")
+
+	if err := taskService.CancelRun(uint(id), runID, c.GetString("username")); err != nil {
+		log.Error(fmt.Sprintf("取消任务执行失败, ID: %d, 批次: %s, 错误: %v", id, runID, err))
+		code := 500
+		if errors.Is(err, service.ErrRunNotFound) {
+			code = 404
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
+			"message": "取消任务执行失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "已发送取消请求",
+	})
+}
+
+// StreamTaskRunOutput 以SSE推送一次执行的实时输出，先回放最近缓冲的输出(可用after指定已收到的最后序号)，
+// 执行结束时发送end事件；消费过慢被断开时发送reset事件，客户端应带上after重新连接
+func StreamTaskRunOutput(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		log.Error(fmt.Sprintf("获取任务输出ID参数错误: %v", err))
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+	runID := c.Param("runId")
+	// 重新连接时传入已收到的最后一行序号，只回放之后的输出
+	after, _ := strconv.ParseInt(c.Query("after"), 10, 64)
+
+	backlog, lines, done, unsubscribe, err := taskService.SubscribeOutput(uint(id), runID, after)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "执行不存在或已结束，请查看任务日志",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	for _, line := range backlog {
+		after = line.Seq
+		c.SSEvent("output", line)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case <-done:
+					c.SSEvent("end", gin.H{"runId": runID})
+				default:
+					// 消费过慢被断开，执行仍在进行，客户端应带上after重新连接
+					c.SSEvent("reset", gin.H{"runId": runID, "after": after, "message": "输出过多，连接已断开，请重新连接"})
+				}
+				return false
+			}
+			after = line.Seq
+			c.SSEvent("output", line)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
 	})
 }
 
@@ -414,7 +834,7 @@ func UpdateTaskStatus(c *gin.Context) {
 		task.ExecStatus = model.TaskExecStatusPending
 	}
 
-	if err := taskService.Update(task); err != nil {
+	if err := taskService.Update(task, currentOperator(c)); err != nil {
 		log.Error(fmt.Sprintf("更新任务状态失败: %v", err))
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -479,7 +899,9 @@ func GetTaskById(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param cronExpr query string true "cron表达式"
-// @Success 200 {array} string "执行时间列表"
+// @Param timezone query string false "IANA时区，如 Asia/Shanghai，默认为服务器时区"
+// @Param calendarId query int false "业务日历ID，跳过日历排除的时间"
+// @Success 200 {array} string "执行时间列表，格式 2006-01-02 15:04:05；同时返回 timezone 和各时间的UTC偏移 offsets"
 // @Router /api/v1/tasks/next-run-times [get]
 func GetNextRunTimes(c *gin.Context) {
 	cronExpr := c.Query("cronExpr")
@@ -491,7 +913,17 @@ func GetNextRunTimes(c *gin.Context) {
 		return
 	}
 
-	times, err := taskService.GetNextRunTimes(cronExpr)
+	calendarID, err := strconv.ParseUint(c.DefaultQuery("calendarId", "0"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的日历ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result, err := taskService.GetNextRunTimes(cronExpr, c.Query("timezone"), uint(calendarID))
 	if err != nil {
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -501,9 +933,357 @@ func GetNextRunTimes(c *gin.Context) {
 		return
 	}
 
+	c.JSON(200, gin.H{
+		"code":     0,
+		"message":  "获取下次执行时间成功",
+		"data":     result.Times,
+		"timezone": result.Timezone,
+		"offsets":  result.Offsets,
+	})
+}
+
+// GetTaskGraph 获取任务依赖图
+// @Summary 获取任务依赖图
+// @Description 返回任务依赖关系的节点和边，指定id时只返回该任务所在的子图
+// @Tags 任务管理
+// @Produce json
+// @Param id query int false "任务ID"
+// @Success 200 {object} model.TaskGraph
+// @Router /api/v1/task/graph [get]
+func GetTaskGraph(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.DefaultQuery("id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	graph, err := taskService.GetDependencyGraph(uint(taskID))
+	if err != nil {
+		log.Error(fmt.Sprintf("获取任务依赖图失败: %v", err))
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取任务依赖图失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取任务依赖图成功",
+		"data":    graph,
+	})
+}
+
+// GetTaskRevisions 获取任务的版本列表
+func GetTaskRevisions(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	revisions, err := taskService.ListRevisions(uint(id))
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "获取任务版本失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取任务版本成功",
+		"data":    revisions,
+	})
+}
+
+// GetTaskRevision 获取任务某个版本的配置
+func GetTaskRevision(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的版本号",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rev, err := taskService.GetRevision(uint(id), revision)
+	if err != nil {
+		code := 500
+		if errors.Is(err, service.ErrRevisionNotFound) {
+			code = 404
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
+			"message": "获取任务版本失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取任务版本成功",
+		"data":    rev.ToResponse(true),
+	})
+}
+
+// DiffTaskRevisions 对比任务的两个版本，参数from、to为版本号
+func DiffTaskRevisions(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的版本号",
+		})
+		return
+	}
+
+	diff, err := taskService.DiffRevisions(uint(id), from, to)
+	if err != nil {
+		code := 500
+		if errors.Is(err, service.ErrRevisionNotFound) {
+			code = 404
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
+			"message": "对比任务版本失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "对比任务版本成功",
+		"data":    diff,
+	})
+}
+
+// RollbackTask 将任务配置回滚到指定版本
+func RollbackTask(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的版本号",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	task, err := taskService.Rollback(uint(id), revision, currentOperator(c))
+	if err != nil {
+		log.Error(fmt.Sprintf("回滚任务失败, ID: %d, 版本: %d, 错误: %v", id, revision, err))
+		code := 500
+		if errors.Is(err, service.ErrRevisionNotFound) {
+			code = 404
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
+			"message": "回滚任务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "回滚任务成功",
+		"data":    task.ToResponse(),
+	})
+}
+
+// ExportTasks 导出任务定义为YAML，参数ids为逗号分隔的任务ID，为空时按名称和类型过滤导出
+func ExportTasks(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	var ids []uint
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": "无效的任务ID",
+				"error":   err.Error(),
+			})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	taskType, _ := strconv.Atoi(c.DefaultQuery("type", "0"))
+
+	data, err := taskService.Export(ids, c.Query("name"), taskType)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "导出任务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=tasks.yaml")
+	c.Data(200, "application/x-yaml; charset=utf-8", data)
+}
+
+// ExportTask 导出单个任务定义为YAML
+func ExportTask(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	task, err := taskService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "任务不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	data, err := taskService.Export([]uint{task.ID}, "", 0)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"code":    500,
+			"message": "导出任务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.yaml", task.Key))
+	c.Data(200, "application/x-yaml; charset=utf-8", data)
+}
+
+// ImportTasks 导入YAML任务定义，请求体为YAML文档。
+// 参数dryRun=true时只返回计划的新建、更新和删除，prune=true时删除清单中没有的任务
+func ImportTasks(c *gin.Context) {
+	// 如果是OPTIONS请求，直接返回
+	if c.Request.Method == "OPTIONS" {
+		c.Status(204)
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil || len(data) == 0 {
+		c.JSON(400, gin.H{
+			"code":    400,
+			"message": "请求体不能为空",
+		})
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+	prune := c.Query("prune") == "true"
+
+	result, err := taskService.Import(data, dryRun, prune, currentOperator(c))
+	if err != nil {
+		log.Error(fmt.Sprintf("导入任务失败: %v", err))
+		code := 500
+		if errors.Is(err, service.ErrInvalidManifest) {
+			code = 400
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
+			"message": "导入任务失败",
+			"error":   err.Error(),
+			"data":    result,
+		})
+		return
+	}
+
+	message := "导入任务成功"
+	if dryRun {
+		message = "导入预演成功"
+	}
 	c.JSON(200, gin.H{
 		"code":    0,
-		"message": "获取下次执行时间成功",
-		"data":    times,
+		"message": message,
+		"data":    result,
 	})
 }
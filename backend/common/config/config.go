@@ -6,10 +6,16 @@ import (
 )
 
 type config struct {
-	Server server `yaml:"server"`
-	Db     db     `yaml:"db"`
-	Logger logger `yaml:"logger"`
-	Redis  redis  `yaml:"redis"`
+	Server     server     `yaml:"server"`
+	Db         db         `yaml:"db"`
+	Logger     logger     `yaml:"logger"`
+	Redis      redis      `yaml:"redis"`
+	Datax      datax      `yaml:"datax"`
+	Scheduler  scheduler  `yaml:"scheduler"`
+	Alert      alert      `yaml:"alert"`
+	TaskLog    taskLog    `yaml:"task_log"`
+	Security   security   `yaml:"security"`
+	SQLConsole sqlConsole `yaml:"sql_console"`
 }
 
 type server struct {
@@ -45,6 +51,59 @@ type redis struct {
 	PoolSize int    `yaml:"pool_size"`
 }
 
+type datax struct {
+	Python string `yaml:"python"` // python解释器
+	Bin    string `yaml:"bin"`    // datax.py路径
+}
+
+type scheduler struct {
+	MaxWorkers     int  `yaml:"max_workers"`     // 同时执行的任务数上限
+	LeaderElection bool `yaml:"leader_election"` // 多副本部署时开启，只有主节点触发定时调度
+	LeaseTTL       int  `yaml:"lease_ttl"`       // 主节点租约时长(秒)，主节点宕机后最迟在此时间后切换
+}
+
+type alert struct {
+	CheckInterval int       `yaml:"check_interval"` // 检查执行超时和未按时开始的间隔(秒)，默认30秒
+	Smtp          alertSmtp `yaml:"smtp"`
+	Sms           alertSms  `yaml:"sms"`
+}
+
+type alertSmtp struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	SSL      bool   `yaml:"ssl"` // 是否使用SSL连接(如465端口)，否则在服务器支持时使用STARTTLS
+}
+
+type alertSms struct {
+	URL   string `yaml:"url"`   // 短信网关地址，以JSON {"phones": [...], "content": "..."} POST
+	Token string `yaml:"token"` // 网关鉴权令牌，以 Authorization: Bearer 发送
+}
+
+type taskLog struct {
+	RetentionDays int    `yaml:"retention_days"` // 全局日志保留天数，为0时不按天数清理
+	RetentionRuns int    `yaml:"retention_runs"` // 全局保留的最近执行批次数，为0时不按批次清理
+	ArchiveDir    string `yaml:"archive_dir"`    // 清理前归档文件的存放目录，多副本部署时必须为各副本挂载的共享存储
+	ArchiveShared bool   `yaml:"archive_shared"` // 归档目录是否为共享存储，开启调度主节点选举时必须为true，否则无法启动
+	PurgeInterval int    `yaml:"purge_interval"` // 清理间隔(分钟)，默认60分钟
+	BatchSize     int    `yaml:"batch_size"`     // 每个归档文件包含的最大日志条数，默认1000
+}
+
+type security struct {
+	MasterKey          string   `yaml:"master_key"`           // 加密敏感配置的主密钥(base64编码的32字节)，环境变量 TOOLS_ADMIN_MASTER_KEY 优先
+	PreviousMasterKeys []string `yaml:"previous_master_keys"` // 轮换前的旧密钥，仅用于解密，环境变量 TOOLS_ADMIN_PREVIOUS_MASTER_KEYS 优先
+}
+
+type sqlConsole struct {
+	ApproverRoleIDs []uint `yaml:"approver_role_ids"` // 变更工单的默认审批角色，数据库连接未指定审批角色时使用
+	QueryTimeout    int    `yaml:"query_timeout"`     // 查询超时(秒)的默认值，数据库连接未指定时使用，默认30秒
+	MaxRows         int    `yaml:"max_rows"`          // 查询返回行数上限的默认值，数据库连接未指定时使用，默认1000行
+	ExportMaxRows   int    `yaml:"export_max_rows"`   // 导出的最大行数，默认100000行
+	ExportTimeout   int    `yaml:"export_timeout"`    // 导出超时(秒)，默认600秒
+}
+
 var Config *config
 
 func init() {
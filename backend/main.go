@@ -1,15 +1,59 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"time"
 	"tools-admin/backend/common/config"
 	"tools-admin/backend/middleware/cors"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/secret"
 	"tools-admin/backend/router"
+	"tools-admin/backend/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 子命令：根据执行日志重建任务统计
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-stats" {
+		if err := rebuildStats(os.Args[2:]); err != nil {
+			fmt.Println("Failed to rebuild task statistics:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	// 子命令：生成主密钥
+	if len(os.Args) > 1 && os.Args[1] == "gen-master-key" {
+		key, err := secret.GenerateKey()
+		if err != nil {
+			fmt.Println("Failed to generate master key:", err)
+			os.Exit(1)
+		}
+		fmt.Println(key)
+		return
+	}
+	// 子命令：用当前主密钥重新加密数据库连接密码和告警通道的敏感配置
+	if len(os.Args) > 1 && os.Args[1] == "rotate-secrets" {
+		result, err := service.NewDatabaseService(db.Db).RotatePasswords()
+		if err != nil {
+			fmt.Println("Failed to rotate secrets:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rotated %d of %d database passwords, %d failed\n", result.Rotated, result.Total, result.Failed)
+		channels, err := (&service.AlertService{}).RotateChannelSecrets()
+		if err != nil {
+			fmt.Println("Failed to rotate secrets:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rotated %d of %d alert channels, %d failed\n", channels.Rotated, channels.Total, channels.Failed)
+		if result.Failed > 0 || channels.Failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 初始化配置
 	server := config.Config.Server
 	r := gin.Default()
@@ -20,8 +64,51 @@ func main() {
 	// 初始化路由
 	router.InitRouter(r)
 
+	// 启动任务调度器
+	if err := service.TaskScheduler.Start(); err != nil {
+		fmt.Println("Failed to start task scheduler:", err)
+	}
+	defer service.TaskScheduler.Stop()
+
+	// 启动任务告警检查
+	service.TaskAlerter.Start()
+	defer service.TaskAlerter.Stop()
+
+	// 启动任务日志清理
+	if err := service.LogRetention.Start(); err != nil {
+		fmt.Println("Failed to start task log retention:", err)
+		os.Exit(1)
+	}
+	defer service.LogRetention.Stop()
+
 	// 启动应用
 	if err := r.Run(":" + server.Port); err != nil {
 		fmt.Println("Failed to run server on port ", server.Port, ":", err)
 	}
 }
+
+// rebuildStats 重建任务统计，用法: rebuild-stats -from 2006-01-02 [-to 2006-01-02] [-force]
+func rebuildStats(args []string) error {
+	fs := flag.NewFlagSet("rebuild-stats", flag.ExitOnError)
+	fromText := fs.String("from", "", "开始日期(含)，格式 2006-01-02")
+	toText := fs.String("to", time.Now().Format("2006-01-02"), "结束日期(含)，默认今天")
+	force := fs.Bool("force", false, "范围内的日志已归档清理时仍然重建")
+	fs.Parse(args)
+
+	from, err := time.ParseInLocation("2006-01-02", *fromText, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %q", *fromText)
+	}
+	to, err := time.ParseInLocation("2006-01-02", *toText, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %q", *toText)
+	}
+
+	result, err := service.TaskStats.Rebuild(from, to, *force)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rebuilt task statistics %s ~ %s: %d days, %d task rows, %d runs\n",
+		result.From, result.To, result.Days, result.Tasks, result.Runs)
+	return nil
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 告警规则类型
+const (
+	AlertRuleFailure             = "failure"              // 执行失败
+	AlertRuleConsecutiveFailures = "consecutive_failures" // 连续失败N次
+	AlertRuleDuration            = "duration"             // 执行时长超过阈值(秒)
+	AlertRuleStartDelay          = "start_delay"          // 超过计划时间X分钟仍未开始执行
+)
+
+// 告警规则类型映射
+var AlertRuleTypeMap = map[string]string{
+	AlertRuleFailure:             "执行失败",
+	AlertRuleConsecutiveFailures: "连续失败",
+	AlertRuleDuration:            "执行超时",
+	AlertRuleStartDelay:          "未按时开始",
+}
+
+// 告警通道类型
+const (
+	AlertChannelWebhook = "webhook"
+	AlertChannelEmail   = "email"
+	AlertChannelSms     = "sms"
+)
+
+// 告警通道类型映射
+var AlertChannelTypeMap = map[string]string{
+	AlertChannelWebhook: "Webhook",
+	AlertChannelEmail:   "邮件",
+	AlertChannelSms:     "短信",
+}
+
+// 告警事件状态
+const (
+	AlertEventFiring   = "firing"   // 告警中
+	AlertEventResolved = "resolved" // 已恢复
+)
+
+// AlertRule 告警规则，TaskID为0时对所有任务生效
+type AlertRule struct {
+	ID          uint           `json:"id" gorm:"primarykey"`
+	Name        string         `json:"name" gorm:"type:varchar(100);not null"`
+	TaskID      uint           `json:"taskId" gorm:"default:0;index"`
+	Type        string         `json:"type" gorm:"type:varchar(30);not null"`
+	Threshold   int            `json:"threshold"`                           // 连续失败次数、执行时长(秒)或开始延迟(分钟)
+	ChannelIDs  IDList         `json:"channelIds" gorm:"type:varchar(255)"` // 告警通道
+	Interval    int            `json:"interval"`                            // 持续告警时重复通知的间隔(分钟)，为0时只通知一次
+	Disabled    bool           `json:"disabled"`                            // 是否停用
+	Description string         `json:"description" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// AlertChannel 告警通道。Config为JSON：
+// webhook: {"url": "...", "headers": {...}}，请求头的值使用主密钥加密存储，响应中以掩码代替，更新时传入掩码表示不修改；
+// email: {"to": ["a@example.com"]}，SMTP服务器在配置文件中设置；
+// sms: {"phones": ["13800000000"]}，短信网关在配置文件中设置
+type AlertChannel struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	Name      string         `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Type      string         `json:"type" gorm:"type:varchar(20);not null"`
+	Config    string         `json:"config" gorm:"type:text"`
+	Disabled  bool           `json:"disabled"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// AlertEvent 告警事件。同一规则和任务同时只有一个告警中的事件(多实例时由唯一索引保证)，
+// 期间再次触发只累计次数，按规则的通知间隔重复通知，恢复时发送恢复通知
+type AlertEvent struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	RuleID         uint       `json:"ruleId" gorm:"not null;index:idx_alert_event_key;uniqueIndex:idx_alert_event_firing"`
+	TaskID         uint       `json:"taskId" gorm:"not null;index:idx_alert_event_key;uniqueIndex:idx_alert_event_firing"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;index:idx_alert_event_key"`
+	Firing         *bool      `json:"-" gorm:"uniqueIndex:idx_alert_event_firing"` // 告警中为true，恢复后为NULL，由唯一索引保证同一规则和任务只有一个告警中的事件
+	RunID          string     `json:"runId" gorm:"type:varchar(32)"`               // 最近一次触发告警的执行批次
+	Message        string     `json:"message" gorm:"type:text"`
+	FireCount      int        `json:"fireCount"`   // 告警期间触发的次数
+	NotifyCount    int        `json:"notifyCount"` // 已发送的告警通知次数
+	FiredAt        time.Time  `json:"firedAt"`
+	LastFiredAt    time.Time  `json:"lastFiredAt"`
+	LastNotifiedAt *time.Time `json:"lastNotifiedAt"`
+	ResolvedAt     *time.Time `json:"resolvedAt"`
+	NotifyError    string     `json:"notifyError" gorm:"type:text"` // 最近一次通知失败的原因
+}
+
+// AlertMessage 发送到告警通道的消息
+type AlertMessage struct {
+	Status   string    `json:"status"` // firing/resolved
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	RuleID   uint      `json:"ruleId"`
+	RuleName string    `json:"ruleName"`
+	RuleType string    `json:"ruleType"`
+	TaskID   uint      `json:"taskId"`
+	TaskName string    `json:"taskName"`
+	RunID    string    `json:"runId,omitempty"`
+	EventID  uint      `json:"eventId"`
+	Time     time.Time `json:"time"`
+}
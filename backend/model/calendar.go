@@ -0,0 +1,164 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 日历规则类型
+const (
+	CalendarRuleDate      = "date"       // 单个日期
+	CalendarRuleDateRange = "date_range" // 日期区间（含首尾）
+	CalendarRuleWindow    = "window"     // 每周重复的时间窗口，如周五12:00至24:00
+)
+
+// 日历规则类型映射
+var CalendarRuleTypeMap = map[string]string{
+	CalendarRuleDate:      "排除日期",
+	CalendarRuleDateRange: "排除日期区间",
+	CalendarRuleWindow:    "排除时间窗口",
+}
+
+const (
+	calendarDateLayout  = "2006-01-02"
+	calendarClockLayout = "15:04"
+)
+
+// Calendar 业务日历，由一组排除规则组成。任务引用日历后，落在排除时间内的调度会被跳过；
+// 日期和时间按任务的时区判断
+type Calendar struct {
+	ID          uint           `json:"id" gorm:"primarykey"`
+	Name        string         `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string         `json:"description" gorm:"type:text"`
+	Rules       []CalendarRule `json:"rules" gorm:"foreignKey:CalendarID"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CalendarRule 日历排除规则
+type CalendarRule struct {
+	ID          uint   `json:"id" gorm:"primarykey"`
+	CalendarID  uint   `json:"calendarId" gorm:"index;not null"`
+	Type        string `json:"type" gorm:"type:varchar(20);not null"`
+	StartDate   string `json:"startDate" gorm:"type:varchar(10)"` // 日期或区间开始日期，格式 2006-01-02
+	EndDate     string `json:"endDate" gorm:"type:varchar(10)"`   // 区间结束日期
+	Weekdays    IDList `json:"weekdays" gorm:"type:varchar(20)"`  // 时间窗口生效的星期，0为周日，为空时每天生效
+	StartTime   string `json:"startTime" gorm:"type:varchar(5)"`  // 时间窗口开始时间，格式 15:04
+	EndTime     string `json:"endTime" gorm:"type:varchar(5)"`    // 时间窗口结束时间（不含），不大于开始时间时跨天，可为24:00
+	Description string `json:"description" gorm:"type:varchar(255)"`
+}
+
+// ExcludedUntil 时间被日历排除时返回排除区间的结束时间(不含)，未被排除时返回零值。
+// 多条规则同时排除时取最晚的结束时间，之后仍可能被其他规则排除
+func (c *Calendar) ExcludedUntil(t time.Time) time.Time {
+	var until time.Time
+	for i := range c.Rules {
+		if end := c.Rules[i].ExcludedUntil(t); end.After(until) {
+			until = end
+		}
+	}
+	return until
+}
+
+// Validate 校验规则格式
+func (r *CalendarRule) Validate() error {
+	switch r.Type {
+	case CalendarRuleDate:
+		if _, err := time.Parse(calendarDateLayout, r.StartDate); err != nil {
+			return fmt.Errorf("无效的日期: %s", r.StartDate)
+		}
+	case CalendarRuleDateRange:
+		start, err := time.Parse(calendarDateLayout, r.StartDate)
+		if err != nil {
+			return fmt.Errorf("无效的开始日期: %s", r.StartDate)
+		}
+		end, err := time.Parse(calendarDateLayout, r.EndDate)
+		if err != nil {
+			return fmt.Errorf("无效的结束日期: %s", r.EndDate)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("结束日期不能早于开始日期")
+		}
+	case CalendarRuleWindow:
+		if !validClock(r.StartTime) {
+			return fmt.Errorf("无效的开始时间: %s", r.StartTime)
+		}
+		if !validClock(r.EndTime) {
+			return fmt.Errorf("无效的结束时间: %s", r.EndTime)
+		}
+		if r.StartTime == r.EndTime {
+			return fmt.Errorf("开始时间和结束时间不能相同")
+		}
+		for _, d := range r.Weekdays {
+			if d > 6 {
+				return fmt.Errorf("无效的星期: %d", d)
+			}
+		}
+	default:
+		return fmt.Errorf("无效的规则类型: %s", r.Type)
+	}
+	return nil
+}
+
+// Excludes 判断时间是否落在规则内
+func (r *CalendarRule) Excludes(t time.Time) bool {
+	date := t.Format(calendarDateLayout)
+	switch r.Type {
+	case CalendarRuleDate:
+		return date == r.StartDate
+	case CalendarRuleDateRange:
+		return date >= r.StartDate && date <= r.EndDate
+	case CalendarRuleWindow:
+		clock := t.Format(calendarClockLayout)
+		weekday := uint(t.Weekday())
+		if r.StartTime < r.EndTime {
+			return r.onWeekday(weekday) && clock >= r.StartTime && clock < r.EndTime
+		}
+		// 跨天窗口：开始当天的开始时间之后，或次日的结束时间之前
+		yesterday := (weekday + 6) % 7
+		return (r.onWeekday(weekday) && clock >= r.StartTime) || (r.onWeekday(yesterday) && clock < r.EndTime)
+	}
+	return false
+}
+
+// ExcludedUntil 时间被规则排除时返回所在排除区间的结束时间(不含)，未被排除时返回零值
+func (r *CalendarRule) ExcludedUntil(t time.Time) time.Time {
+	if !r.Excludes(t) {
+		return time.Time{}
+	}
+	switch r.Type {
+	case CalendarRuleDate:
+		return dayAt(t, 0, "24:00")
+	case CalendarRuleDateRange:
+		end, _ := time.ParseInLocation(calendarDateLayout, r.EndDate, t.Location())
+		return dayAt(end, 0, "24:00")
+	}
+	// 跨天窗口在开始当天排除到次日的结束时间
+	if r.StartTime >= r.EndTime && t.Format(calendarClockLayout) >= r.StartTime {
+		return dayAt(t, 1, r.EndTime)
+	}
+	return dayAt(t, 0, r.EndTime)
+}
+
+// dayAt 返回t所在日期之后第days天的clock时刻，clock为24:00时为次日零点
+func dayAt(t time.Time, days int, clock string) time.Time {
+	var hour, minute int
+	fmt.Sscanf(clock, "%d:%d", &hour, &minute)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, hour, minute, 0, 0, t.Location())
+}
+
+func (r *CalendarRule) onWeekday(weekday uint) bool {
+	return len(r.Weekdays) == 0 || r.Weekdays.Contains(weekday)
+}
+
+// validClock 校验 15:04 格式的时间，允许24:00表示当天结束
+func validClock(clock string) bool {
+	if clock == "24:00" {
+		return true
+	}
+	_, err := time.Parse(calendarClockLayout, clock)
+	return err == nil && len(clock) == len(calendarClockLayout)
+}
@@ -11,11 +11,49 @@ type TaskStatistics struct {
 	TotalCount   int       `json:"total_count"`
 	SuccessCount int       `json:"success_count"`
 	FailCount    int       `json:"fail_count"`
+	CancelCount  int       `json:"cancel_count"`
 	SuccessRate  float64   `json:"success_rate" gorm:"type:decimal(5,2)"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// TaskDailyStatistics 按任务的每日执行统计，用于按任务、按类型分析
+type TaskDailyStatistics struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	Date         time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_task_daily_stat"`
+	TaskID       uint      `json:"task_id" gorm:"uniqueIndex:idx_task_daily_stat"`
+	TaskType     TaskType  `json:"task_type" gorm:"type:tinyint;index"`
+	TotalCount   int       `json:"total_count"`
+	SuccessCount int       `json:"success_count"`
+	FailCount    int       `json:"fail_count"`
+	CancelCount  int       `json:"cancel_count"`
+	SuccessRate  float64   `json:"success_rate" gorm:"type:decimal(5,2)"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TaskStatisticsBreakdown 一段时间内按任务或按类型汇总的执行统计
+type TaskStatisticsBreakdown struct {
+	TaskID       uint    `json:"task_id,omitempty"`
+	TaskName     string  `json:"task_name,omitempty"`
+	TaskType     int8    `json:"task_type"`
+	TypeName     string  `json:"type_name"`
+	TotalCount   int     `json:"total_count"`
+	SuccessCount int     `json:"success_count"`
+	FailCount    int     `json:"fail_count"`
+	CancelCount  int     `json:"cancel_count"`
+	SuccessRate  float64 `json:"success_rate"`
+}
+
+// TaskStatisticsRebuildResult 重建任务统计的结果
+type TaskStatisticsRebuildResult struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Days  int    `json:"days"`  // 有执行记录的天数
+	Tasks int    `json:"tasks"` // 按任务统计的记录数
+	Runs  int    `json:"runs"`  // 计入统计的执行批次数
+}
+
 // SmsStatistics 短信统计
 type SmsStatistics struct {
 	ID           uint      `json:"id" gorm:"primarykey"`
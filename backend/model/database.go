@@ -10,12 +10,57 @@ type Database struct {
 	Host      string    `json:"host" gorm:"size:255;not null;comment:主机地址"`
 	Port      int       `json:"port" gorm:"not null;comment:端口"`
 	Username  string    `json:"username" gorm:"size:50;not null;comment:用户名"`
-	Password  string    `json:"password" gorm:"size:255;not null;comment:密码"`
+	Password  string    `json:"-" gorm:"size:512;not null;comment:密码(加密存储)"`
 	Database  string    `json:"database" gorm:"size:50;not null;comment:数据库名"`
+	ApproverRoleIDs IDList `json:"approver_role_ids" gorm:"type:varchar(255);comment:变更工单审批角色，为空时使用全局配置"`
+	QueryTimeout int       `json:"query_timeout" gorm:"default:0;comment:查询超时(秒)，为0时使用全局配置"`
+	MaxRows   int          `json:"max_rows" gorm:"default:0;comment:查询返回行数上限，为0时使用全局配置"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PasswordMask 响应中代替密码返回的掩码，更新时传入掩码表示不修改密码
+const PasswordMask = "******"
+
+// DatabaseResponse 数据库连接响应，密码以掩码代替
+type DatabaseResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	Database  string    `json:"database"`
+	ApproverRoleIDs IDList `json:"approver_role_ids"`
+	QueryTimeout int       `json:"query_timeout"`
+	MaxRows   int          `json:"max_rows"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ToResponse 转换为响应对象
+func (d *Database) ToResponse() *DatabaseResponse {
+	resp := &DatabaseResponse{
+		ID:        d.ID,
+		Name:      d.Name,
+		Type:      d.Type,
+		Host:      d.Host,
+		Port:      d.Port,
+		Username:  d.Username,
+		Database:  d.Database,
+		ApproverRoleIDs: d.ApproverRoleIDs,
+		QueryTimeout: d.QueryTimeout,
+		MaxRows:   d.MaxRows,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+	if d.Password != "" {
+		resp.Password = PasswordMask
+	}
+	return resp
+}
+
 // DatabaseListReq 数据库列表请求
 type DatabaseListReq struct {
 	Page     int    `form:"page" binding:"required,min=1"`
@@ -26,8 +71,8 @@ type DatabaseListReq struct {
 
 // DatabaseListResp 数据库列表响应
 type DatabaseListResp struct {
-	Total int64      `json:"total"`
-	List  []Database `json:"list"`
+	Total int64               `json:"total"`
+	List  []*DatabaseResponse `json:"list"`
 }
 
 // DatabaseCreateReq 创建数据库连接请求
@@ -39,6 +84,9 @@ type DatabaseCreateReq struct {
 	Username string `json:"username" binding:"required,max=50"`
 	Password string `json:"password" binding:"required,max=255"`
 	Database string `json:"database" binding:"required,max=50"`
+	ApproverRoleIDs IDList `json:"approver_role_ids"`
+	QueryTimeout int    `json:"query_timeout" binding:"min=0,max=3600"` // 为0时使用全局配置
+	MaxRows  int        `json:"max_rows" binding:"min=0,max=100000"`    // 为0时使用全局配置
 }
 
 // DatabaseUpdateReq 更新数据库连接请求
@@ -49,12 +97,16 @@ type DatabaseUpdateReq struct {
 	Host     string `json:"host" binding:"required,max=255"`
 	Port     int    `json:"port" binding:"required,min=1,max=65535"`
 	Username string `json:"username" binding:"required,max=50"`
-	Password string `json:"password" binding:"required,max=255"`
+	Password string `json:"password" binding:"max=255"` // 为空或为掩码时不修改密码
 	Database string `json:"database" binding:"required,max=50"`
+	ApproverRoleIDs IDList `json:"approver_role_ids"`
+	QueryTimeout int    `json:"query_timeout" binding:"min=0,max=3600"` // 为0时使用全局配置
+	MaxRows  int        `json:"max_rows" binding:"min=0,max=100000"`    // 为0时使用全局配置
 }
 
-// DatabaseTestReq 测试数据库连接请求
+// DatabaseTestReq 测试数据库连接请求。编辑已有连接时传入ID，密码为掩码时使用已保存的连接配置测试
 type DatabaseTestReq struct {
+	ID       uint   `json:"id"`
 	Type     string `json:"type" binding:"required,oneof=mysql postgresql"`
 	Host     string `json:"host" binding:"required"`
 	Port     int    `json:"port" binding:"required"`
@@ -63,16 +115,30 @@ type DatabaseTestReq struct {
 	Database string `json:"database" binding:"required"`
 }
 
-// QueryExecuteReq SQL查询请求
+// SecretRotationResult 重新加密敏感配置的结果
+type SecretRotationResult struct {
+	Total   int `json:"total"`
+	Rotated int `json:"rotated"` // 重新加密的条数
+	Failed  int `json:"failed"`  // 无法解密的条数
+}
+
+// QueryExecuteReq SQL查询请求。QueryID由调用方生成，用于在查询返回前取消查询，为空时自动生成。
+// 指定Export时以文件形式流式返回查询结果
 type QueryExecuteReq struct {
 	DatabaseID uint   `json:"database_id" binding:"required"`
 	SQL        string `json:"sql" binding:"required"`
+	QueryID    string `json:"query_id" binding:"max=64"`
+	Export     string `json:"export" binding:"omitempty,oneof=csv xlsx ndjson"` // 导出格式
 }
 
 // QueryExecuteResp SQL查询响应
 type QueryExecuteResp struct {
-	Columns []string        `json:"columns"`
-	Rows    [][]interface{} `json:"rows"`
+	QueryID   string          `json:"query_id"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Truncated bool            `json:"truncated"` // 结果超过行数上限，只返回了前 MaxRows 行
+	MaxRows   int             `json:"max_rows"`
+	Duration  int64           `json:"duration"` // 执行时长(毫秒)
 }
 
 // TableListReq 获取表列表请求
@@ -14,6 +14,8 @@ type SQLAudit struct {
 	Error       string    `json:"error" gorm:"type:text;comment:错误信息"`
 	AffectedRows int64    `json:"affected_rows" gorm:"comment:影响行数"`
 	ClientIP    string    `json:"client_ip" gorm:"size:50;not null;comment:客户端IP"`
+	Action      SQLAuditAction `json:"action" gorm:"size:20;default:query;index;comment:操作类型"`
+	TicketID    uint      `json:"ticket_id" gorm:"index;comment:变更工单ID"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -26,9 +28,39 @@ const (
 	RiskHigh   SQLRisk = "high"   // 高风险
 )
 
-// SQLAnalysisResult SQL分析结果
+// SQLAnalysisResult SQL分析结果，Risk、Description和Suggestion取风险最高的一项发现
 type SQLAnalysisResult struct {
 	Risk        SQLRisk `json:"risk"`         // 风险级别
 	Description string  `json:"description"`   // 风险描述
 	Suggestion  string  `json:"suggestion"`    // 优化建议
+	Statements  []*SQLStatementInfo `json:"statements"` // 各语句的解析结果
+	Findings    []*SQLFinding       `json:"findings"`   // 全部发现，按风险从高到低排列
+}
+
+// SQLStatementInfo 一条语句的解析结果
+type SQLStatementInfo struct {
+	SQL       string   `json:"sql"`
+	Type      string   `json:"type"`       // 语句类型(read/dml/ddl/dcl/tcl/other)
+	Verb      string   `json:"verb"`       // 语句动词，如 SELECT、DELETE、CREATE TABLE
+	Tables    []string `json:"tables"`     // 涉及的表
+	Writes    []string `json:"writes"`     // 修改的对象
+	HasWhere  bool     `json:"has_where"`
+	HasLimit  bool     `json:"has_limit"`
+	Locking   bool     `json:"locking"`    // 加锁读，如 FOR UPDATE
+}
+
+// SQLFinding SQL分析发现的一项问题
+type SQLFinding struct {
+	Rule       string  `json:"rule"`       // 规则标识，如 delete_without_where
+	Risk       SQLRisk `json:"risk"`
+	Statement  int     `json:"statement"`  // 所在语句的序号，从1开始，0表示整体
+	Message    string  `json:"message"`
+	Suggestion string  `json:"suggestion"`
+}
+
+// SQLAnalyzeReq SQL分析请求，指定数据库时按数据库类型选择SQL方言
+type SQLAnalyzeReq struct {
+	DatabaseID uint   `json:"database_id"`
+	Type       string `json:"type" binding:"omitempty,oneof=mysql postgresql"`
+	SQL        string `json:"sql" binding:"required"`
 }
@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+// SQLTicketStatus SQL变更工单状态
+type SQLTicketStatus string
+
+const (
+	TicketPending   SQLTicketStatus = "pending"   // 待审批
+	TicketApproved  SQLTicketStatus = "approved"  // 已审批，待执行
+	TicketRejected  SQLTicketStatus = "rejected"  // 已驳回
+	TicketCancelled SQLTicketStatus = "cancelled" // 已撤销
+	TicketExecuting SQLTicketStatus = "executing" // 执行中
+	TicketExecuted  SQLTicketStatus = "executed"  // 执行成功
+	TicketFailed    SQLTicketStatus = "failed"    // 执行失败，事务已回滚
+)
+
+// SQLAuditAction SQL审计记录的操作类型
+type SQLAuditAction string
+
+const (
+	AuditQuery   SQLAuditAction = "query"   // 控制台查询
+	AuditSubmit  SQLAuditAction = "submit"  // 提交变更工单
+	AuditApprove SQLAuditAction = "approve" // 审批通过
+	AuditReject  SQLAuditAction = "reject"  // 驳回
+	AuditCancel  SQLAuditAction = "cancel"  // 撤销
+	AuditExecute SQLAuditAction = "execute" // 执行变更
+	AuditExport  SQLAuditAction = "export"  // 导出查询结果
+)
+
+// SQLTicket SQL变更工单。提交时按数据库配置确定审批角色，审批通过后在事务中执行，
+// 数据修改的影响行数与预期不一致时回滚
+type SQLTicket struct {
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	DatabaseID      uint            `json:"database_id" gorm:"not null;index;comment:数据库ID"`
+	SQL             string          `json:"sql" gorm:"type:text;not null;comment:变更SQL"`
+	Reason          string          `json:"reason" gorm:"size:500;not null;comment:变更原因"`
+	StatementType   string          `json:"statement_type" gorm:"size:20;comment:语句类型(dml/ddl)"`
+	Risk            SQLRisk         `json:"risk" gorm:"size:20;comment:分析风险级别"`
+	ExpectedRows    int64           `json:"expected_rows" gorm:"comment:预期影响行数"`
+	AffectedRows    int64           `json:"affected_rows" gorm:"comment:实际影响行数"`
+	ApproverRoleIDs IDList          `json:"approver_role_ids" gorm:"type:varchar(255);comment:审批角色"`
+	Status          SQLTicketStatus `json:"status" gorm:"size:20;not null;index;comment:状态"`
+	SubmitterID     uint            `json:"submitter_id" gorm:"not null;index;comment:提交人ID"`
+	SubmitterName   string          `json:"submitter_name" gorm:"size:50;comment:提交人"`
+	ApproverID      uint            `json:"approver_id" gorm:"comment:审批人ID"`
+	ApproverName    string          `json:"approver_name" gorm:"size:50;comment:审批人"`
+	ApproveComment  string          `json:"approve_comment" gorm:"size:500;comment:审批意见"`
+	ApprovedAt      *time.Time      `json:"approved_at"`
+	ExecutorID      uint            `json:"executor_id" gorm:"comment:执行人ID"`
+	ExecutorName    string          `json:"executor_name" gorm:"size:50;comment:执行人"`
+	ExecutedAt      *time.Time      `json:"executed_at"`
+	Error           string          `json:"error" gorm:"type:text;comment:执行错误"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// SQLTicketCreateReq 提交变更工单请求，数据修改需声明预期影响行数
+type SQLTicketCreateReq struct {
+	DatabaseID   uint   `json:"database_id" binding:"required"`
+	SQL          string `json:"sql" binding:"required"`
+	Reason       string `json:"reason" binding:"required,max=500"`
+	ExpectedRows *int64 `json:"expected_rows" binding:"omitempty,min=0"`
+}
+
+// SQLTicketReviewReq 审批或驳回请求
+type SQLTicketReviewReq struct {
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+// SQLTicketListReq 变更工单列表请求
+type SQLTicketListReq struct {
+	Page       int    `form:"page" binding:"required,min=1"`
+	PageSize   int    `form:"pageSize" binding:"required,min=1,max=100"`
+	DatabaseID uint   `form:"database_id"`
+	Status     string `form:"status"`
+	Mine       bool   `form:"mine"`       // 只看自己提交的
+	Reviewable bool   `form:"reviewable"` // 只看待自己审批的
+}
+
+// SQLTicketListResp 变更工单列表响应
+type SQLTicketListResp struct {
+	Total int64        `json:"total"`
+	List  []*SQLTicket `json:"list"`
+}
+
+// SQLTicketDetail 变更工单详情，包含分析结果和审计记录
+type SQLTicketDetail struct {
+	*SQLTicket
+	Analysis *SQLAnalysisResult `json:"analysis"`
+	Audits   []*SQLAudit        `json:"audits"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -35,8 +36,87 @@ const (
 	TaskExecStatusRunning  TaskExecStatus = 2 // 执行中
 	TaskExecStatusSuccess  TaskExecStatus = 3 // 执行成功
 	TaskExecStatusFailed   TaskExecStatus = 4 // 执行失败
+	TaskExecStatusCancelled TaskExecStatus = 5 // 已取消
+	TaskExecStatusSkipped   TaskExecStatus = 6 // 已跳过
 )
 
+// 重试退避策略
+const (
+	RetryBackoffFixed       = "fixed"       // 固定间隔
+	RetryBackoffExponential = "exponential" // 指数退避
+)
+
+// 执行失败类型
+const (
+	FailureKindTimeout  = "timeout"   // 执行超时
+	FailureKindExitCode = "exit_code" // 非零退出码
+	FailureKindHttp5xx  = "http_5xx"  // HTTP 5xx响应
+	FailureKindOther    = "other"     // 其他错误，如参数错误、断言失败
+)
+
+// 可配置重试的失败类型
+var RetryableFailureKinds = map[string]bool{
+	FailureKindTimeout:  true,
+	FailureKindExitCode: true,
+	FailureKindHttp5xx:  true,
+}
+
+// 依赖触发规则
+const (
+	TriggerRuleAllSuccess = "all_success" // 所有上游均执行成功
+	TriggerRuleAnyDone    = "any_done"    // 任一上游执行结束
+	TriggerRuleAllDone    = "all_done"    // 所有上游均执行结束（不论成功失败）
+)
+
+// 依赖触发规则映射
+var TriggerRuleMap = map[string]string{
+	TriggerRuleAllSuccess: "所有上游成功",
+	TriggerRuleAnyDone:    "任一上游结束",
+	TriggerRuleAllDone:    "所有上游结束",
+}
+
+// 并发策略，上一次执行未结束时如何处理新的触发
+const (
+	ConcurrencyAllow   = "allow"   // 允许并行执行
+	ConcurrencyForbid  = "forbid"  // 跳过新的触发
+	ConcurrencyReplace = "replace" // 取消正在进行的执行，再开始新的执行
+)
+
+// 并发策略映射
+var ConcurrencyPolicyMap = map[string]string{
+	ConcurrencyAllow:   "允许并行",
+	ConcurrencyForbid:  "禁止并行",
+	ConcurrencyReplace: "替换旧执行",
+}
+
+// 错过调度的处理策略，服务停机期间错过的cron触发在调度器启动时按此处理
+const (
+	MisfireFireOnce = "fire_once" // 立即补执行一次
+	MisfireFireAll  = "fire_all"  // 按错过的次数依次补执行，最多 MisfireLimit 次
+	MisfireSkip     = "skip"      // 不补执行，等待下一次触发
+)
+
+// 错过调度处理策略映射
+var MisfirePolicyMap = map[string]string{
+	MisfireFireOnce: "补执行一次",
+	MisfireFireAll:  "全部补执行",
+	MisfireSkip:     "跳过",
+}
+
+// 任务优先级
+const (
+	TaskPriorityHigh   = "high"
+	TaskPriorityMedium = "medium"
+	TaskPriorityLow    = "low"
+)
+
+// 任务优先级排序值，值越小越先执行
+var TaskPriorityRank = map[string]int{
+	TaskPriorityHigh:   0,
+	TaskPriorityMedium: 1,
+	TaskPriorityLow:    2,
+}
+
 // 任务类型映射
 var TaskTypeMap = map[TaskType]string{
 	TaskTypeShell:     "shell",
@@ -60,11 +140,14 @@ var TaskExecStatusMap = map[TaskExecStatus]string{
 	TaskExecStatusRunning: "running",
 	TaskExecStatusSuccess: "success",
 	TaskExecStatusFailed:  "failed",
+	TaskExecStatusCancelled: "cancelled",
+	TaskExecStatusSkipped:   "skipped",
 }
 
 // Task 任务模型
 type Task struct {
 	gorm.Model
+	Key         string        `json:"key" gorm:"column:task_key;type:varchar(100);default:null;uniqueIndex:uk_tasks_task_key"` // 任务标识，导入导出时用于匹配任务，由唯一索引保证唯一，删除任务时置空
 	Name        string        `json:"name" gorm:"type:varchar(100);not null"`
 	Type        TaskType      `json:"type" gorm:"type:tinyint;default:1"`
 	Description string        `json:"description" gorm:"type:text"`
@@ -72,15 +155,36 @@ type Task struct {
 	ExecStatus  TaskExecStatus `json:"execStatus" gorm:"type:tinyint;default:1"` // 默认为待执行状态
 	Priority    string        `json:"priority" gorm:"type:varchar(20);default:'medium'"`
 	CronExpr    string        `json:"cronExpr" gorm:"type:varchar(100)"`
+	Timezone    string        `json:"timezone" gorm:"type:varchar(64)"` // IANA时区，如 Asia/Shanghai，为空时使用服务器时区
+	CalendarID  uint          `json:"calendarId" gorm:"default:0;index"` // 业务日历，日历排除的时间不调度
 	NextRunTime *time.Time    `json:"nextRunTime"`
 	LastRunTime *time.Time    `json:"lastRunTime"`
 	TaskContent string        `json:"taskContent" gorm:"type:text"`
 	TaskParams  string        `json:"taskParams" gorm:"type:text"`
+
+	RetryMaxAttempts int    `json:"retryMaxAttempts" gorm:"default:1"`                   // 最大执行次数(含首次)
+	RetryBackoff     string `json:"retryBackoff" gorm:"type:varchar(20);default:'fixed'"` // 退避策略 fixed/exponential
+	RetryInterval    int    `json:"retryInterval" gorm:"default:0"`                      // 重试间隔(秒)，指数退避时为首次间隔
+	RetryOn          string `json:"retryOn" gorm:"type:varchar(100)"`                    // 需要重试的失败类型，逗号分隔
+
+	UpstreamIDs IDList `json:"upstreamIds" gorm:"type:varchar(255)"`                     // 上游任务ID
+	TriggerRule string `json:"triggerRule" gorm:"type:varchar(20);default:'all_success'"` // 依赖触发规则
+
+	ConcurrencyPolicy string `json:"concurrencyPolicy" gorm:"type:varchar(20);default:'allow'"` // 并发策略
+
+	MisfirePolicy string `json:"misfirePolicy" gorm:"type:varchar(20);default:'skip'"` // 错过调度的处理策略
+	MisfireLimit  int    `json:"misfireLimit" gorm:"default:10"`                       // 全部补执行时的最大次数
+
+	LogRetentionDays int `json:"logRetentionDays" gorm:"default:0"` // 日志保留天数，为0时使用全局配置
+	LogRetentionRuns int `json:"logRetentionRuns" gorm:"default:0"` // 日志保留的最近执行批次数，为0时使用全局配置
+
+	Revision int `json:"revision" gorm:"default:0"` // 当前配置的版本号
 }
 
 // TaskResponse 任务响应
 type TaskResponse struct {
 	ID          uint          `json:"id"`
+	Key         string        `json:"key"`
 	Name        string        `json:"name"`
 	Type        int8          `json:"type"`
 	Description string        `json:"description"`
@@ -89,16 +193,60 @@ type TaskResponse struct {
 	Priority    string        `json:"priority"`
 	CreateTime  time.Time     `json:"createTime"`
 	CronExpr    string        `json:"cronExpr"`
+	Timezone    string        `json:"timezone"`
+	CalendarID  uint          `json:"calendarId"`
 	NextRunTime *time.Time    `json:"nextRunTime"`
 	LastRunTime *time.Time    `json:"lastRunTime"`
 	TaskContent string        `json:"taskContent"`
 	TaskParams  string        `json:"taskParams"`
+
+	RetryMaxAttempts int    `json:"retryMaxAttempts"`
+	RetryBackoff     string `json:"retryBackoff"`
+	RetryInterval    int    `json:"retryInterval"`
+	RetryOn          string `json:"retryOn"`
+
+	UpstreamIDs IDList `json:"upstreamIds"`
+	TriggerRule string `json:"triggerRule"`
+
+	ConcurrencyPolicy string `json:"concurrencyPolicy"`
+
+	MisfirePolicy string `json:"misfirePolicy"`
+	MisfireLimit  int    `json:"misfireLimit"`
+
+	LogRetentionDays int `json:"logRetentionDays"`
+	LogRetentionRuns int `json:"logRetentionRuns"`
+
+	Revision int `json:"revision"`
+}
+
+// TaskGraphNode 依赖图节点
+type TaskGraphNode struct {
+	ID         uint   `json:"id"`
+	Name       string `json:"name"`
+	Type       int8   `json:"type"`
+	Status     int8   `json:"status"`
+	ExecStatus int8   `json:"execStatus"`
+	CronExpr   string `json:"cronExpr"`
+	TriggerRule string `json:"triggerRule"`
+}
+
+// TaskGraphEdge 依赖图的边，From为上游，To为下游
+type TaskGraphEdge struct {
+	From uint `json:"from"`
+	To   uint `json:"to"`
+}
+
+// TaskGraph 任务依赖图
+type TaskGraph struct {
+	Nodes []*TaskGraphNode `json:"nodes"`
+	Edges []*TaskGraphEdge `json:"edges"`
 }
 
 // ToResponse 转换为响应对象
 func (t *Task) ToResponse() *TaskResponse {
 	return &TaskResponse{
 		ID:          t.ID,
+		Key:         t.Key,
 		Name:        t.Name,
 		Type:        int8(t.Type),
 		Description: t.Description,
@@ -107,11 +255,77 @@ func (t *Task) ToResponse() *TaskResponse {
 		Priority:    t.Priority,
 		CreateTime:  t.CreatedAt,
 		CronExpr:    t.CronExpr,
+		Timezone:    t.Timezone,
+		CalendarID:  t.CalendarID,
 		NextRunTime: t.NextRunTime,
 		LastRunTime: t.LastRunTime,
 		TaskContent: t.TaskContent,
 		TaskParams:  t.TaskParams,
+
+		RetryMaxAttempts: t.RetryMaxAttempts,
+		RetryBackoff:     t.RetryBackoff,
+		RetryInterval:    t.RetryInterval,
+		RetryOn:          t.RetryOn,
+
+		UpstreamIDs: t.UpstreamIDs,
+		TriggerRule: t.TriggerRule,
+
+		ConcurrencyPolicy: t.ConcurrencyPolicy,
+
+		MisfirePolicy: t.MisfirePolicy,
+		MisfireLimit:  t.MisfireLimit,
+
+		LogRetentionDays: t.LogRetentionDays,
+		LogRetentionRuns: t.LogRetentionRuns,
+
+		Revision: t.Revision,
+	}
+}
+
+// RetryOnKinds 返回需要重试的失败类型
+func (t *Task) RetryOnKinds() []string {
+	var kinds []string
+	for _, kind := range strings.Split(t.RetryOn, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
 	}
+	return kinds
+}
+
+// RetryDelay 返回第attempt次执行失败后的等待时间
+func (t *Task) RetryDelay(attempt int) time.Duration {
+	delay := time.Duration(t.RetryInterval) * time.Second
+	if t.RetryBackoff == RetryBackoffExponential {
+		for i := 1; i < attempt && delay < time.Hour; i++ {
+			delay *= 2
+		}
+		if delay > time.Hour {
+			delay = time.Hour
+		}
+	}
+	return delay
+}
+
+// PriorityRank 返回任务优先级的排序值，未知优先级按中优先级处理
+func (t *Task) PriorityRank() int {
+	if rank, ok := TaskPriorityRank[t.Priority]; ok {
+		return rank
+	}
+	return TaskPriorityRank[TaskPriorityMedium]
+}
+
+// ShouldRetry 判断第attempt次执行以failureKind失败后是否需要重试
+func (t *Task) ShouldRetry(attempt int, failureKind string) bool {
+	if attempt >= t.RetryMaxAttempts {
+		return false
+	}
+	for _, kind := range t.RetryOnKinds() {
+		if kind == failureKind {
+			return true
+		}
+	}
+	return false
 }
 
 // String 实现 TaskType 的字符串方法
@@ -119,6 +333,26 @@ func (t TaskType) String() string {
 	return TaskTypeMap[t]
 }
 
+// ParseTaskType 根据类型名称获取任务类型
+func ParseTaskType(name string) (TaskType, bool) {
+	for t, n := range TaskTypeMap {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// ParseTaskExecStatus 根据状态名称获取执行状态
+func ParseTaskExecStatus(name string) (TaskExecStatus, bool) {
+	for s, n := range TaskExecStatusMap {
+		if n == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
 // String 实现 TaskStatus 的字符串方法
 func (s TaskStatus) String() string {
 	return TaskStatusMap[s]
@@ -132,22 +366,49 @@ func (s TaskExecStatus) String() string {
 // TaskLog 任务日志模型
 type TaskLog struct {
 	gorm.Model
-	TaskID     uint          `json:"taskId" gorm:"not null"`                // 任务ID
+	TaskID     uint          `json:"taskId" gorm:"not null;index"`          // 任务ID
+	RunID      string        `json:"runId" gorm:"type:varchar(32);index"`  // 执行批次ID，同一次执行的多次重试共用
+	Revision   int           `json:"revision"`                             // 执行时任务配置的版本号
+	Attempt    int           `json:"attempt" gorm:"default:1"`             // 第几次尝试
+	Retried    bool          `json:"retried"`                              // 失败后已重试，为true时不是该批次的最终结果
+	FailureKind string       `json:"failureKind" gorm:"type:varchar(20)"`  // 失败类型
 	Status     TaskExecStatus `json:"status" gorm:"type:tinyint;not null"` // 执行状态
-	Output     string        `json:"output" gorm:"type:text"`              // 执行输出
-	Error      string        `json:"error" gorm:"type:text"`               // 错误信息
+	Output     string        `json:"output" gorm:"type:mediumtext"`        // 执行输出
+	Error      string        `json:"error" gorm:"type:mediumtext"`         // 错误信息
+	ExitCode   int           `json:"exitCode"`                             // 退出码
+	Metrics    string        `json:"metrics" gorm:"type:text"`             // 执行指标(JSON)
+	CancelledBy string       `json:"cancelledBy" gorm:"type:varchar(50)"`  // 取消人
+	RunParams  string        `json:"runParams" gorm:"type:text"`           // 本次执行的参数(JSON)，默认参数与传入参数合并后的结果
+	RenderedContent string   `json:"renderedContent" gorm:"type:mediumtext"` // 渲染后的任务内容，内容不含模板时为空
+	RenderedParams  string   `json:"renderedParams" gorm:"type:mediumtext"`  // 渲染后的任务参数，参数不含模板时为空
 	StartTime  time.Time     `json:"startTime" gorm:"not null"`            // 开始时间
 	EndTime    time.Time     `json:"endTime" gorm:"not null"`             // 结束时间
 	Duration   int64         `json:"duration" gorm:"not null"`             // 执行时长（秒）
 }
 
+// IsFinal 是否为执行批次的最终结果，只有最终结果计入任务统计
+func (l *TaskLog) IsFinal() bool {
+	return !l.Retried
+}
+
 // TaskLogResponse 任务日志响应
 type TaskLogResponse struct {
 	ID        uint          `json:"id"`
 	TaskID    uint          `json:"taskId"`
+	RunID     string        `json:"runId"`
+	Revision  int           `json:"revision"`
+	Attempt   int           `json:"attempt"`
+	Retried   bool          `json:"retried"`
+	FailureKind string      `json:"failureKind"`
 	Status    int8          `json:"status"`
 	Output    string        `json:"output"`
 	Error     string        `json:"error"`
+	ExitCode  int           `json:"exitCode"`
+	Metrics   string        `json:"metrics"`
+	CancelledBy string      `json:"cancelledBy"`
+	RunParams string        `json:"runParams"`
+	RenderedContent string  `json:"renderedContent"`
+	RenderedParams  string  `json:"renderedParams"`
 	StartTime time.Time     `json:"startTime"`
 	EndTime   time.Time     `json:"endTime"`
 	Duration  int64         `json:"duration"`
@@ -158,11 +419,50 @@ func (l *TaskLog) ToResponse() *TaskLogResponse {
 	return &TaskLogResponse{
 		ID:        l.ID,
 		TaskID:    l.TaskID,
+		RunID:     l.RunID,
+		Revision:  l.Revision,
+		Attempt:   l.Attempt,
+		Retried:   l.Retried,
+		FailureKind: l.FailureKind,
 		Status:    int8(l.Status),
 		Output:    l.Output,
 		Error:     l.Error,
+		ExitCode:  l.ExitCode,
+		Metrics:   l.Metrics,
+		CancelledBy: l.CancelledBy,
+		RunParams: l.RunParams,
+		RenderedContent: l.RenderedContent,
+		RenderedParams:  l.RenderedParams,
 		StartTime: l.StartTime,
 		EndTime:   l.EndTime,
 		Duration:  l.Duration,
 	}
 }
+
+// TaskRunInfo 正在进行的任务执行
+type TaskRunInfo struct {
+	RunID       string    `json:"runId"`
+	TaskID      uint      `json:"taskId"`
+	Queued      bool      `json:"queued"`      // 是否在排队等待执行
+	Attempt     int       `json:"attempt"`     // 当前第几次尝试
+	StartTime   time.Time `json:"startTime"`
+	ScheduledTime time.Time `json:"scheduledTime"` // 计划执行时间
+	CancelledBy string    `json:"cancelledBy"` // 不为空时表示正在取消
+}
+
+// TaskOutputLine 执行过程中实时输出的一行
+type TaskOutputLine struct {
+	Seq     int64     `json:"seq"`     // 序号，同一次执行内递增
+	Attempt int       `json:"attempt"` // 第几次尝试
+	Stream  string    `json:"stream"`  // stdout/stderr/system
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+}
+
+// NextRunTimes 未来几次执行时间，Times 为所在时区的墙上时间(2006-01-02 15:04:05)，
+// Offsets 为对应时间的UTC偏移(如 +08:00)，夏令时重复区间内的同一墙上时间据此区分
+type NextRunTimes struct {
+	Timezone string   `json:"timezone"`
+	Times    []string `json:"times"`
+	Offsets  []string `json:"offsets"`
+}
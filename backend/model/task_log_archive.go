@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// TaskLogQuery 任务日志查询条件，为零值的条件不过滤
+type TaskLogQuery struct {
+	Page      int
+	PageSize  int
+	Status    TaskExecStatus
+	RunID     string
+	StartFrom *time.Time // 开始时间不早于
+	StartTo   *time.Time // 开始时间早于
+	Keyword   string     // 在输出和错误信息中搜索
+}
+
+// TaskLogRetention 任务日志保留策略，两项都为0时不清理
+type TaskLogRetention struct {
+	Days int `json:"days"` // 保留天数，早于该天数的日志被清理
+	Runs int `json:"runs"` // 保留的最近执行批次数，更早批次的日志被清理
+}
+
+// Enabled 是否需要清理
+func (r TaskLogRetention) Enabled() bool {
+	return r.Days > 0 || r.Runs > 0
+}
+
+// TaskLogArchive 清理前归档的任务日志文件，文件为gzip压缩的JSON Lines，每行一条日志
+type TaskLogArchive struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	TaskID    uint      `json:"taskId" gorm:"not null;index"`
+	FileName  string    `json:"fileName" gorm:"type:varchar(255);not null"` // 相对于归档目录的路径
+	Node      string    `json:"node" gorm:"type:varchar(100)"`              // 写入归档的实例
+	Size      int64     `json:"size"`                                       // 文件大小(字节)
+	Count     int       `json:"count"`                                      // 日志条数
+	MinLogID  uint      `json:"minLogId"`
+	MaxLogID  uint      `json:"maxLogId"`
+	StartFrom time.Time `json:"startFrom"` // 最早一条日志的开始时间
+	StartTo   time.Time `json:"startTo"`   // 最晚一条日志的开始时间
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TaskLogPurgeResult 一次清理的结果
+type TaskLogPurgeResult struct {
+	TaskID   uint `json:"taskId"`
+	Purged   int  `json:"purged"`   // 清理的日志条数
+	Archives int  `json:"archives"` // 生成的归档文件数
+}
@@ -0,0 +1,71 @@
+package model
+
+// TaskManifest 任务定义清单，导入导出的YAML文档
+type TaskManifest struct {
+	Tasks []*TaskDefinition `yaml:"tasks"`
+}
+
+// TaskDefinition 以YAML描述的任务定义。依赖通过上游任务的标识引用，日历通过名称引用，
+// 不包含数据库ID、执行状态等与环境相关的字段；内容和参数中的凭据在导出时会被脱敏
+type TaskDefinition struct {
+	Key         string `yaml:"key"`
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description,omitempty"`
+	Enabled     bool   `yaml:"enabled"`
+	Priority    string `yaml:"priority,omitempty"`
+	Cron        string `yaml:"cron,omitempty"`
+	Timezone    string `yaml:"timezone,omitempty"`
+	Calendar    string `yaml:"calendar,omitempty"`
+	Content     string `yaml:"content,omitempty"`
+	Params      string `yaml:"params,omitempty"`
+
+	Retry *TaskRetryDefinition `yaml:"retry,omitempty"`
+
+	Upstreams   []string `yaml:"upstreams,omitempty"`
+	TriggerRule string   `yaml:"triggerRule,omitempty"`
+
+	ConcurrencyPolicy string `yaml:"concurrencyPolicy,omitempty"`
+	MisfirePolicy     string `yaml:"misfirePolicy,omitempty"`
+	MisfireLimit      int    `yaml:"misfireLimit,omitempty"`
+
+	LogRetentionDays int `yaml:"logRetentionDays,omitempty"`
+	LogRetentionRuns int `yaml:"logRetentionRuns,omitempty"`
+}
+
+// TaskRetryDefinition 重试策略
+type TaskRetryDefinition struct {
+	MaxAttempts int    `yaml:"maxAttempts"`
+	Backoff     string `yaml:"backoff,omitempty"`
+	Interval    int    `yaml:"interval,omitempty"`
+	On          string `yaml:"on,omitempty"`
+}
+
+// 导入计划中的操作
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionDelete    = "delete"
+	ImportActionUnchanged = "unchanged"
+)
+
+// TaskImportItem 导入计划中的一项
+type TaskImportItem struct {
+	Action  string   `json:"action"`
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`
+	TaskID  uint     `json:"taskId,omitempty"`
+	Changes []string `json:"changes,omitempty"` // 更新时有变化的字段
+	Error   string   `json:"error,omitempty"`   // 执行失败的原因
+}
+
+// TaskImportResult 导入结果，预演时只包含计划不执行
+type TaskImportResult struct {
+	DryRun    bool              `json:"dryRun"`
+	Applied   bool              `json:"applied"` // 计划是否已全部执行
+	Creates   int               `json:"creates"`
+	Updates   int               `json:"updates"`
+	Deletes   int               `json:"deletes"`
+	Unchanged int               `json:"unchanged"`
+	Items     []*TaskImportItem `json:"items"`
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 版本来源
+const (
+	RevisionActionCreate   = "create"   // 创建任务
+	RevisionActionUpdate   = "update"   // 修改任务
+	RevisionActionRollback = "rollback" // 回滚到历史版本
+	RevisionActionBaseline = "baseline" // 启用版本记录前已存在的任务，首次修改时记录的原始配置
+)
+
+// 版本来源映射
+var RevisionActionMap = map[string]string{
+	RevisionActionCreate:   "创建",
+	RevisionActionUpdate:   "修改",
+	RevisionActionRollback: "回滚",
+	RevisionActionBaseline: "原始版本",
+}
+
+// Operator 操作人，来自登录令牌
+type Operator struct {
+	UserID   uint
+	Username string
+}
+
+// TaskSpec 任务的可编辑配置，每个版本保存一份完整快照。
+// 启停状态和执行状态不属于配置，修改它们不产生新版本
+type TaskSpec struct {
+	Name        string   `json:"name"`
+	Type        TaskType `json:"type"`
+	Description string   `json:"description"`
+	Priority    string   `json:"priority"`
+	CronExpr    string   `json:"cronExpr"`
+	Timezone    string   `json:"timezone"`
+	CalendarID  uint     `json:"calendarId"`
+	TaskContent string   `json:"taskContent"`
+	TaskParams  string   `json:"taskParams"`
+
+	RetryMaxAttempts int    `json:"retryMaxAttempts"`
+	RetryBackoff     string `json:"retryBackoff"`
+	RetryInterval    int    `json:"retryInterval"`
+	RetryOn          string `json:"retryOn"`
+
+	UpstreamIDs IDList `json:"upstreamIds"`
+	TriggerRule string `json:"triggerRule"`
+
+	ConcurrencyPolicy string `json:"concurrencyPolicy"`
+
+	MisfirePolicy string `json:"misfirePolicy"`
+	MisfireLimit  int    `json:"misfireLimit"`
+
+	LogRetentionDays int `json:"logRetentionDays"`
+	LogRetentionRuns int `json:"logRetentionRuns"`
+}
+
+// Spec 返回任务当前的配置
+func (t *Task) Spec() *TaskSpec {
+	return &TaskSpec{
+		Name:        t.Name,
+		Type:        t.Type,
+		Description: t.Description,
+		Priority:    t.Priority,
+		CronExpr:    t.CronExpr,
+		Timezone:    t.Timezone,
+		CalendarID:  t.CalendarID,
+		TaskContent: t.TaskContent,
+		TaskParams:  t.TaskParams,
+
+		RetryMaxAttempts: t.RetryMaxAttempts,
+		RetryBackoff:     t.RetryBackoff,
+		RetryInterval:    t.RetryInterval,
+		RetryOn:          t.RetryOn,
+
+		UpstreamIDs: t.UpstreamIDs,
+		TriggerRule: t.TriggerRule,
+
+		ConcurrencyPolicy: t.ConcurrencyPolicy,
+
+		MisfirePolicy: t.MisfirePolicy,
+		MisfireLimit:  t.MisfireLimit,
+
+		LogRetentionDays: t.LogRetentionDays,
+		LogRetentionRuns: t.LogRetentionRuns,
+	}
+}
+
+// ApplySpec 用配置覆盖任务的可编辑字段
+func (t *Task) ApplySpec(spec *TaskSpec) {
+	t.Name = spec.Name
+	t.Type = spec.Type
+	t.Description = spec.Description
+	t.Priority = spec.Priority
+	t.CronExpr = spec.CronExpr
+	t.Timezone = spec.Timezone
+	t.CalendarID = spec.CalendarID
+	t.TaskContent = spec.TaskContent
+	t.TaskParams = spec.TaskParams
+
+	t.RetryMaxAttempts = spec.RetryMaxAttempts
+	t.RetryBackoff = spec.RetryBackoff
+	t.RetryInterval = spec.RetryInterval
+	t.RetryOn = spec.RetryOn
+
+	t.UpstreamIDs = spec.UpstreamIDs
+	t.TriggerRule = spec.TriggerRule
+
+	t.ConcurrencyPolicy = spec.ConcurrencyPolicy
+
+	t.MisfirePolicy = spec.MisfirePolicy
+	t.MisfireLimit = spec.MisfireLimit
+
+	t.LogRetentionDays = spec.LogRetentionDays
+	t.LogRetentionRuns = spec.LogRetentionRuns
+}
+
+// TaskRevision 任务配置的历史版本，创建后不再修改
+type TaskRevision struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	TaskID       uint      `json:"taskId" gorm:"not null;uniqueIndex:idx_task_revision"`
+	Revision     int       `json:"revision" gorm:"not null;uniqueIndex:idx_task_revision"` // 版本号，从1开始递增
+	Action       string    `json:"action" gorm:"type:varchar(20);not null"`
+	RollbackFrom int       `json:"rollbackFrom"`                      // 回滚时对应的历史版本号
+	Spec         string    `json:"-" gorm:"type:mediumtext;not null"` // 配置快照(JSON)
+	AuthorID     uint      `json:"authorId"`
+	AuthorName   string    `json:"authorName" gorm:"type:varchar(50)"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// NewTaskRevision 创建任务当前配置的版本
+func NewTaskRevision(task *Task, action string, operator Operator) (*TaskRevision, error) {
+	data, err := json.Marshal(task.Spec())
+	if err != nil {
+		return nil, err
+	}
+	return &TaskRevision{
+		TaskID:     task.ID,
+		Revision:   task.Revision,
+		Action:     action,
+		Spec:       string(data),
+		AuthorID:   operator.UserID,
+		AuthorName: operator.Username,
+	}, nil
+}
+
+// ParseSpec 解析版本的配置快照
+func (r *TaskRevision) ParseSpec() (*TaskSpec, error) {
+	var spec TaskSpec
+	if err := json.Unmarshal([]byte(r.Spec), &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// TaskRevisionResponse 任务版本响应
+type TaskRevisionResponse struct {
+	*TaskRevision
+	ActionName string    `json:"actionName"`
+	Spec       *TaskSpec `json:"spec,omitempty"`
+}
+
+// ToResponse 转换为响应对象，withSpec为true时带上配置快照
+func (r *TaskRevision) ToResponse(withSpec bool) *TaskRevisionResponse {
+	resp := &TaskRevisionResponse{
+		TaskRevision: r,
+		ActionName:   RevisionActionMap[r.Action],
+	}
+	if withSpec {
+		resp.Spec, _ = r.ParseSpec()
+	}
+	return resp
+}
+
+// TaskFieldDiff 两个版本间一个字段的差异，文本字段带逐行对比
+type TaskFieldDiff struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	Lines []*DiffLine `json:"lines,omitempty"`
+}
+
+// 逐行对比的行类型
+const (
+	DiffLineEqual  = " "
+	DiffLineDelete = "-"
+	DiffLineInsert = "+"
+)
+
+// DiffLine 逐行对比的一行
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// TaskRevisionDiff 两个版本的差异
+type TaskRevisionDiff struct {
+	TaskID  uint             `json:"taskId"`
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []*TaskFieldDiff `json:"changes"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IDList ID列表，数据库中以逗号分隔的字符串存储，JSON中为数组
+type IDList []uint
+
+// Value 实现 driver.Valuer 接口
+func (l IDList) Value() (driver.Value, error) {
+	parts := make([]string, 0, len(l))
+	for _, id := range l {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (l *IDList) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	default:
+		return fmt.Errorf("无法将 %T 转换为 IDList", value)
+	}
+
+	list := IDList{}
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return fmt.Errorf("无效的ID: %s", part)
+		}
+		list = append(list, uint(id))
+	}
+	*l = list
+	return nil
+}
+
+// Contains 判断列表中是否包含指定ID
+func (l IDList) Contains(id uint) bool {
+	for _, v := range l {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -7,24 +7,69 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
+// Parser 支持秒级的六段式cron表达式解析器
+var Parser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Parse 解析cron表达式，按服务器本地时区计算
+func Parse(cronExpr string) (cron.Schedule, error) {
+	return ParseInLocation(cronExpr, time.Local)
+}
+
+// ParseInLocation 解析cron表达式，按指定时区的墙上时间计算执行时间
+func ParseInLocation(cronExpr string, loc *time.Location) (cron.Schedule, error) {
+	schedule, err := Parser.Parse(cronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %v", err)
+	}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		// @every 等固定间隔的表达式与时区无关
+		return schedule, nil
+	}
+	return newZonedSchedule(spec, loc), nil
+}
+
+// LoadLocation 加载IANA时区，为空时使用服务器本地时区
+func LoadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %s", timezone)
+	}
+	return loc, nil
+}
+
+// ParseWithTimezone 按IANA时区名称解析cron表达式
+func ParseWithTimezone(cronExpr, timezone string) (cron.Schedule, error) {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInLocation(cronExpr, loc)
+}
+
 // GetNextRunTime 根据cron表达式计算下次执行时间
-func GetNextRunTime(cronExpr string) (*time.Time, error) {
-	return GetNextRunTimeFrom(cronExpr, time.Now())
+func GetNextRunTime(cronExpr, timezone string) (*time.Time, error) {
+	return GetNextRunTimeFrom(cronExpr, timezone, time.Now())
 }
 
 // GetNextRunTimeFrom 从指定时间开始计算下次执行时间
-func GetNextRunTimeFrom(cronExpr string, from time.Time) (*time.Time, error) {
+func GetNextRunTimeFrom(cronExpr, timezone string, from time.Time) (*time.Time, error) {
 	if cronExpr == "" {
 		return nil, nil
 	}
 
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
+	schedule, err := ParseWithTimezone(cronExpr, timezone)
 	if err != nil {
-		return nil, fmt.Errorf("invalid cron expression: %v", err)
+		return nil, err
 	}
 
 	next := schedule.Next(from)
+	if next.IsZero() {
+		return nil, nil
+	}
 	return &next, nil
 }
 
@@ -33,12 +78,14 @@ func ValidateCronExpr(cronExpr string) error {
 	if cronExpr == "" {
 		return nil
 	}
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	_, err := parser.Parse(cronExpr)
-	if err != nil {
-		return fmt.Errorf("invalid cron expression: %v", err)
-	}
-	return nil
+	_, err := Parse(cronExpr)
+	return err
+}
+
+// ValidateTimezone 验证时区是否有效
+func ValidateTimezone(timezone string) error {
+	_, err := LoadLocation(timezone)
+	return err
 }
 
 // CommonPatterns 常用的cron表达式模式（包含秒）
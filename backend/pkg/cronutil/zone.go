@@ -0,0 +1,129 @@
+package cronutil
+
+import (
+	"time"
+	_ "time/tzdata" // 内置时区数据，部署环境缺少 tzdata 时也能加载IANA时区
+
+	"github.com/robfig/cron/v3"
+)
+
+// allHours 小时字段包含全部24小时
+const allHours = 1<<24 - 1
+
+// zonedSchedule 按指定时区的墙上时间计算执行时间，并处理夏令时切换：
+//   - 小时字段为*的表达式（如每分钟、每小时）按实际经过的时间执行，切换当天不会多执行或少执行；
+//   - 其他表达式按墙上时间执行：落在跳过区间（如 02:30 在当天不存在）的触发在切换时刻执行一次，
+//     重复区间内的触发只在第一次出现时执行
+type zonedSchedule struct {
+	spec *cron.SpecSchedule
+	loc  *time.Location
+	wall bool // 是否按墙上时间计算
+}
+
+func newZonedSchedule(spec *cron.SpecSchedule, loc *time.Location) *zonedSchedule {
+	copied := *spec
+	z := &zonedSchedule{spec: &copied, loc: loc}
+	if spec.Hour&allHours == allHours {
+		copied.Location = loc
+	} else {
+		// 在UTC中计算墙上时间，UTC没有夏令时
+		copied.Location = time.UTC
+		z.wall = true
+	}
+	return z
+}
+
+// Next 返回t之后的下一次执行时间，时区与t相同；五年内没有匹配时返回零值
+func (z *zonedSchedule) Next(t time.Time) time.Time {
+	if !z.wall {
+		return z.spec.Next(t.In(z.loc)).In(t.Location())
+	}
+
+	wall := toWall(t.In(z.loc))
+	for {
+		wall = z.spec.Next(wall)
+		if wall.IsZero() {
+			return time.Time{}
+		}
+		next := z.fromWall(wall)
+		if next.After(t) {
+			return next.In(t.Location())
+		}
+		// 重复区间内第一次出现已经过去，继续查找
+	}
+}
+
+// toWall 将时间的墙上时间表示为UTC时间
+func toWall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// fromWall 将墙上时间转换为时区中的时刻：不存在的墙上时间取夏令时切换时刻，
+// 出现两次的墙上时间取较早的一次
+func (z *zonedSchedule) fromWall(wall time.Time) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, z.loc)
+	if !toWall(t).Equal(wall) {
+		return z.gapEnd(t, wall)
+	}
+
+	// 切换前后的偏移不同，检查同一墙上时间是否更早出现过
+	_, before := t.Add(-12 * time.Hour).Zone()
+	_, after := t.Add(12 * time.Hour).Zone()
+	if diff := time.Duration(before-after) * time.Second; diff > 0 {
+		if earlier := t.Add(-diff); toWall(earlier).Equal(wall) {
+			return earlier
+		}
+	}
+	return t
+}
+
+// gapEnd 查找墙上时间首次不早于wall的时刻，即跳过区间结束、夏令时切换的时刻
+func (z *zonedSchedule) gapEnd(t, wall time.Time) time.Time {
+	lo, hi := t.Add(-12*time.Hour), t.Add(12*time.Hour)
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+		if toWall(mid.In(z.loc)).Before(wall) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi.In(z.loc)
+}
+
+// maxExcludedSkips 连续跳过的排除区间个数上限，超过后认为不会再有执行时间
+const maxExcludedSkips = 100000
+
+// excludeSchedule 跳过被排除的触发时间
+type excludeSchedule struct {
+	schedule      cron.Schedule
+	excludedUntil func(time.Time) time.Time
+}
+
+// Exclude 包装调度，跳过被排除的触发时间。excludedUntil 在时间被排除时返回所在排除区间的结束时间(不含)，
+// 未被排除时返回零值
+func Exclude(schedule cron.Schedule, excludedUntil func(time.Time) time.Time) cron.Schedule {
+	return &excludeSchedule{schedule: schedule, excludedUntil: excludedUntil}
+}
+
+// Next 返回t之后第一个未被排除的执行时间，找不到时返回零值。
+// 触发时间被排除时直接从排除区间结束处继续查找，不逐个检查区间内的触发
+func (s *excludeSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxExcludedSkips; i++ {
+		next := s.schedule.Next(t)
+		if next.IsZero() {
+			return next
+		}
+		until := s.excludedUntil(next)
+		if until.IsZero() {
+			return next
+		}
+		if until.After(next) {
+			// 调度从下一整秒开始查找，从结束时间前一刻查找可以得到结束时间本身
+			t = until.Add(-time.Nanosecond)
+		} else {
+			t = next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,127 @@
+package cronutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestZonedScheduleDST(t *testing.T) {
+	loc, err := LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-03-10 02:00 EST 跳到 03:00 EDT，2024-11-03 02:00 EDT 回到 01:00 EST
+	tests := []struct {
+		name  string
+		expr  string
+		start string
+		want  []string
+	}{
+		{name: "spring forward runs skipped time at transition", expr: "0 30 2 * * *", start: "2024-03-09T12:00:00-05:00",
+			want: []string{"2024-03-10T03:00:00-04:00", "2024-03-11T02:30:00-04:00"}},
+		{name: "spring forward keeps times after gap", expr: "0 30 3 * * *", start: "2024-03-09T12:00:00-05:00",
+			want: []string{"2024-03-10T03:30:00-04:00", "2024-03-11T03:30:00-04:00"}},
+		{name: "fall back runs repeated time once", expr: "0 30 1 * * *", start: "2024-11-02T12:00:00-04:00",
+			want: []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"}},
+		{name: "fall back skips second occurrence", expr: "0 30 1 * * *", start: "2024-11-03T01:45:00-04:00",
+			want: []string{"2024-11-04T01:30:00-05:00"}},
+		{name: "hourly across spring forward", expr: "0 0 * * * *", start: "2024-03-10T00:30:00-05:00",
+			want: []string{"2024-03-10T01:00:00-05:00", "2024-03-10T03:00:00-04:00", "2024-03-10T04:00:00-04:00"}},
+		{name: "hourly across fall back", expr: "0 0 * * * *", start: "2024-11-03T00:30:00-04:00",
+			want: []string{"2024-11-03T01:00:00-04:00", "2024-11-03T01:00:00-05:00", "2024-11-03T02:00:00-05:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseInLocation(tt.expr, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, err := time.Parse(time.RFC3339, tt.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				want, err := time.Parse(time.RFC3339, w)
+				if err != nil {
+					t.Fatal(err)
+				}
+				next = schedule.Next(next)
+				if !next.Equal(want) {
+					t.Fatalf("Next() = %s, want %s", next.In(loc).Format(time.RFC3339), w)
+				}
+			}
+		})
+	}
+}
+
+func TestExcludeSkipsWholeInterval(t *testing.T) {
+	loc, err := LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每秒执行，排除30天：逐秒检查需要两百多万次
+	schedule, err := ParseInLocation("* * * * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 30)
+	calls := 0
+	excluded := Exclude(schedule, func(t time.Time) time.Time {
+		calls++
+		if !t.Before(start) && t.Before(end) {
+			return end
+		}
+		return time.Time{}
+	})
+
+	next := excluded.Next(start.Add(-time.Second))
+	if !next.Equal(end) {
+		t.Errorf("Next() = %s, want %s", next, end)
+	}
+	if calls > 2 {
+		t.Errorf("excludedUntil called %d times, want at most 2", calls)
+	}
+}
+
+func TestExcludeWallClockSchedule(t *testing.T) {
+	loc, err := LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每天9点执行，排除周末
+	schedule, err := ParseInLocation("0 0 9 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluded := Exclude(schedule, func(t time.Time) time.Time {
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			days := 1
+			if t.Weekday() == time.Saturday {
+				days = 2
+			}
+			return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+		}
+		return time.Time{}
+	})
+
+	// 2024-01-05 是周五
+	next := excluded.Next(time.Date(2024, 1, 5, 10, 0, 0, 0, loc))
+	if want := time.Date(2024, 1, 8, 9, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("Next() = %s, want %s", next, want)
+	}
+}
+
+func TestExcludeNeverRuns(t *testing.T) {
+	schedule, err := Parse("0 * * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluded := Exclude(schedule, func(t time.Time) time.Time {
+		return t.Add(time.Hour)
+	})
+	if next := excluded.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Next() = %s, want zero", next)
+	}
+}
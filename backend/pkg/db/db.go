@@ -40,17 +40,91 @@ func init() {
 	sqlDB.SetMaxOpenConns(db.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(time.Duration(db.ConnMaxLifetime) * time.Second)
 
+	// 任务标识由普通索引改为唯一索引，需在迁移前处理已有数据
+	if Db.Migrator().HasColumn(&model.Task{}, "task_key") {
+		if err := migrateTaskKeys(); err != nil {
+			log.Error("迁移任务标识失败: " + err.Error())
+			panic(err)
+		}
+	}
+
 	// 自动迁移数据库
 	err = Db.AutoMigrate(
 		&model.User{},
 		&model.Menu{},
 		&model.Task{},
 		&model.TaskLog{},
+		&model.TaskLogArchive{},
+		&model.TaskStatistics{},
+		&model.TaskDailyStatistics{},
+		&model.TaskRevision{},
+		&model.Calendar{},
+		&model.CalendarRule{},
+		&model.AlertRule{},
+		&model.AlertChannel{},
+		&model.AlertEvent{},
+		&model.Database{},
+		&model.SQLAudit{},
+		&model.SQLTicket{},
 	)
 	if err != nil {
 		log.Error("数据库迁移失败: " + err.Error())
 		panic(err)
 	}
 
+	// 为没有标识的任务补全标识，首次升级时标识列由上面的迁移新增，已有任务的标识为NULL
+	if err := Db.Model(&model.Task{}).Where("task_key = '' OR task_key IS NULL").
+		Update("task_key", gorm.Expr("CONCAT('task-', id)")).Error; err != nil {
+		log.Error("补全任务标识失败: " + err.Error())
+		panic(err)
+	}
+
+	// 为告警中的事件补全唯一标记，同一规则和任务重复的告警中事件只保留最新的一个
+	var events []*model.AlertEvent
+	if err := Db.Select("id").Where("status = ? AND firing IS NULL", model.AlertEventFiring).
+		Order("id desc").Find(&events).Error; err != nil {
+		log.Error("补全告警事件标记失败: " + err.Error())
+	}
+	for _, event := range events {
+		if err := Db.Model(event).Update("firing", true).Error; err != nil {
+			Db.Model(event).Updates(map[string]interface{}{"status": model.AlertEventResolved, "resolved_at": time.Now()})
+		}
+	}
+
 	log.Info("数据库初始化成功!")
 }
+
+// migrateTaskKeys 为创建唯一索引准备任务标识：已删除任务的标识置空，未删除任务的空标识补全为 task-<id>，
+// 重复的标识只保留最早创建的任务，其余改为 task-<id>，最后删除原有的普通索引
+func migrateTaskKeys() error {
+	if err := Db.Unscoped().Model(&model.Task{}).Where("deleted_at IS NOT NULL AND task_key IS NOT NULL").
+		Update("task_key", nil).Error; err != nil {
+		return err
+	}
+	if err := Db.Model(&model.Task{}).Where("task_key = '' OR task_key IS NULL").
+		Update("task_key", gorm.Expr("CONCAT('task-', id)")).Error; err != nil {
+		return err
+	}
+
+	var duplicates []string
+	if err := Db.Model(&model.Task{}).Group("task_key").Having("COUNT(*) > 1").
+		Pluck("task_key", &duplicates).Error; err != nil {
+		return err
+	}
+	for _, key := range duplicates {
+		var ids []uint
+		if err := Db.Model(&model.Task{}).Where("task_key = ?", key).Order("id").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := Db.Model(&model.Task{}).Where("id IN ?", ids[1:]).
+			Update("task_key", gorm.Expr("CONCAT('task-', id)")).Error; err != nil {
+			return err
+		}
+		log.Info(fmt.Sprintf("任务标识 %s 重复, 任务 %v 的标识已改为 task-<id>", key, ids[1:]))
+	}
+
+	if Db.Migrator().HasIndex(&model.Task{}, "idx_tasks_task_key") {
+		return Db.Migrator().DropIndex(&model.Task{}, "idx_tasks_task_key")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// utf8BOM 使Excel按UTF-8打开CSV
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+	rows   int
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := w.Write(utf8BOM); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteHeader(columns []Column) error {
+	c.record = make([]string, len(columns))
+	for i, col := range columns {
+		c.record[i] = col.Name
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	for i, v := range values {
+		c.record[i] = formatText(v)
+	}
+	if err := c.w.Write(c.record); err != nil {
+		return err
+	}
+	// 定期刷新，让客户端尽早收到数据
+	if c.rows++; c.rows%500 == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,127 @@
+// Package export 将查询结果逐行写出为CSV、XLSX或NDJSON，不在内存中缓存全部结果
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	CSV    Format = "csv"
+	XLSX   Format = "xlsx"
+	NDJSON Format = "ndjson"
+)
+
+// MaxXLSXRows XLSX工作表的最大行数(不含表头)
+const MaxXLSXRows = 1048575
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// Column 导出的列
+type Column struct {
+	Name    string
+	Numeric bool // 数值列，文本形式的值(如MySQL文本协议返回的数值)按数值输出
+}
+
+// Writer 逐行写出查询结果，写完后需调用Close
+type Writer interface {
+	// WriteHeader 写出列名，需在WriteRow之前调用一次
+	WriteHeader(columns []Column) error
+	// WriteRow 写出一行，值为database/sql扫描得到的原始值
+	WriteRow(values []interface{}) error
+	// Close 写出格式的结尾部分，不关闭底层的io.Writer
+	Close() error
+}
+
+// New 创建指定格式的Writer
+func New(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w)
+	case XLSX:
+		return newXLSXWriter(w)
+	case NDJSON:
+		return newNDJSONWriter(w), nil
+	}
+	return nil, fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// ContentType 返回导出格式的MIME类型
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case NDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
+
+// Extension 返回导出文件的扩展名
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Valid 是否为支持的导出格式
+func (f Format) Valid() bool {
+	return f == CSV || f == XLSX || f == NDJSON
+}
+
+// formatText 将扫描得到的值转换为文本，NULL为空字符串
+func formatText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(timeLayout)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// numericTypes 数值类型的列类型名(sql.ColumnType.DatabaseTypeName)
+var numericTypes = map[string]bool{
+	"TINYINT": true, "SMALLINT": true, "MEDIUMINT": true, "INT": true, "INTEGER": true, "BIGINT": true,
+	"DECIMAL": true, "NUMERIC": true, "FLOAT": true, "DOUBLE": true, "REAL": true, "YEAR": true,
+	"INT2": true, "INT4": true, "INT8": true, "FLOAT4": true, "FLOAT8": true,
+}
+
+// IsNumericType 列类型名是否为数值类型，MySQL无符号类型带 UNSIGNED 前缀
+func IsNumericType(databaseTypeName string) bool {
+	return numericTypes[strings.TrimPrefix(strings.ToUpper(databaseTypeName), "UNSIGNED ")]
+}
+
+// numberText 返回文本形式的数值，不是合法的JSON数值(如NaN、Infinity)时返回false
+func numberText(v interface{}) (string, bool) {
+	var text string
+	switch v := v.(type) {
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return "", false
+	}
+	if text == "" || !(text[0] == '-' || (text[0] >= '0' && text[0] <= '9')) || !json.Valid([]byte(text)) {
+		return "", false
+	}
+	return text, true
+}
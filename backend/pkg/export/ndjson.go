@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ndjsonWriter 每行一个JSON对象，按列的顺序输出字段
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	keys    [][]byte // 编码后的列名
+	numeric []bool
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{w: bufio.NewWriter(w)}
+}
+
+func (n *ndjsonWriter) WriteHeader(columns []Column) error {
+	n.keys = make([][]byte, len(columns))
+	n.numeric = make([]bool, len(columns))
+	for i, col := range columns {
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return err
+		}
+		n.keys[i] = key
+		n.numeric[i] = col.Numeric
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(values []interface{}) error {
+	n.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		n.w.Write(n.keys[i])
+		n.w.WriteByte(':')
+		// 数值按原文输出，不经过float64，避免DECIMAL丢失精度
+		if text, ok := numberText(v); ok && n.numeric[i] {
+			n.w.WriteString(text)
+			continue
+		}
+		value, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return err
+		}
+		n.w.Write(value)
+	}
+	n.w.WriteByte('}')
+	_, err := n.w.WriteString("\n")
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+// jsonValue 字节数组按文本输出，时间按 timeLayout 格式输出
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(timeLayout)
+	}
+	return v
+}
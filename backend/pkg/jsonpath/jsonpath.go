@@ -0,0 +1,84 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Get 按JSONPath从已解析的JSON数据中取值
+// 支持 $.a.b、$.list[0].name、$['key'] 等常用写法
+func Get(data interface{}, path string) (interface{}, error) {
+	tokens, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := data
+	for _, token := range tokens {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			value, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("路径 %s 不存在: %s", path, token)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, fmt.Errorf("路径 %s 中的数组下标无效: %s", path, token)
+			}
+			if index < 0 {
+				index += len(v)
+			}
+			if index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("路径 %s 中的数组下标越界: %s", path, token)
+			}
+			current = v[index]
+		default:
+			return nil, fmt.Errorf("路径 %s 不存在: %s", path, token)
+		}
+	}
+	return current, nil
+}
+
+// parse 将JSONPath拆分为逐级访问的键
+func parse(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath必须以$开头: %s", path)
+	}
+
+	var tokens []string
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("JSONPath格式错误: %s", path)
+			}
+			tokens = append(tokens, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("JSONPath缺少右括号: %s", path)
+			}
+			token := strings.TrimSpace(rest[1:end])
+			token = strings.Trim(token, `'"`)
+			if token == "" {
+				return nil, fmt.Errorf("JSONPath格式错误: %s", path)
+			}
+			tokens = append(tokens, token)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("JSONPath格式错误: %s", path)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// renewScript 仅当租约仍由owner持有时续期
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 仅当租约仍由owner持有时删除
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// claimScript 仅当fencing token仍是最新值时写入key（SET NX），
+// 旧的持有者在失去租约后无法再写入
+var claimScript = redis.NewScript(`
+if redis.call("GET", KEYS[2]) ~= ARGV[1] then
+	return -1
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// ErrStaleToken fencing token已过期，说明租约已被其他实例获取
+var ErrStaleToken = errors.New("fencing token已过期")
+
+// Lease 基于 SET NX 和过期时间的租约。持有者需在ttl内续约，
+// 进程退出或续约失败后租约最迟在ttl后过期，可被其他实例获取。
+// 每次获取租约都会生成递增的fencing token，用于拒绝旧持有者的写入
+type Lease struct {
+	rc    *RClient
+	key   string
+	owner string
+	ttl   time.Duration
+	token int64
+}
+
+// NewLease 创建租约，owner用于区分不同的持有者
+func (rc *RClient) NewLease(key, owner string, ttl time.Duration) *Lease {
+	return &Lease{rc: rc, key: key, owner: owner, ttl: ttl}
+}
+
+// SetNX key不存在时设置值和过期时间，返回是否设置成功
+func (rc *RClient) SetNX(key, value string, ex time.Duration) (bool, error) {
+	return rc.client.SetNX(rc.ctx, key, value, ex).Result()
+}
+
+// fencingKey 保存fencing token的key
+func (l *Lease) fencingKey() string {
+	return l.key + ":fencing"
+}
+
+// Acquire 尝试获取租约，成功时生成新的fencing token
+func (l *Lease) Acquire() (bool, error) {
+	ok, err := l.rc.SetNX(l.key, l.owner, l.ttl)
+	if err != nil || !ok {
+		return false, err
+	}
+	token, err := l.rc.client.Incr(l.rc.ctx, l.fencingKey()).Result()
+	if err != nil {
+		l.Release()
+		return false, err
+	}
+	l.token = token
+	return true, nil
+}
+
+// Renew 续约，租约已过期或被其他实例持有时返回false
+func (l *Lease) Renew() (bool, error) {
+	n, err := renewScript.Run(l.rc.ctx, l.rc.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Release 主动释放租约，仅当仍持有时生效
+func (l *Lease) Release() error {
+	return releaseScript.Run(l.rc.ctx, l.rc.client, []string{l.key}, l.owner).Err()
+}
+
+// Token 返回最近一次获取租约时生成的fencing token
+func (l *Lease) Token() int64 {
+	return l.token
+}
+
+// Claim 以当前fencing token写入一次性的key（SET NX），
+// 用于保证同一件事只被执行一次；token已过期时返回 ErrStaleToken
+func (l *Lease) Claim(key string, ex time.Duration) (bool, error) {
+	n, err := claimScript.Run(l.rc.ctx, l.rc.client, []string{key, l.fencingKey()}, l.token, ex.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	if n < 0 {
+		return false, ErrStaleToken
+	}
+	return n == 1, nil
+}
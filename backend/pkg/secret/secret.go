@@ -0,0 +1,191 @@
+// Package secret 使用主密钥对敏感配置(如数据库连接密码)进行AES-256-GCM加密存储。
+// 密文格式为 enc:v1:<密钥指纹>:<base64(nonce+密文)>，指纹用于在轮换密钥后找到对应的解密密钥
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"tools-admin/backend/common/config"
+)
+
+const (
+	prefix  = "enc:v1:"
+	keySize = 32
+
+	// 环境变量优先于配置文件
+	envMasterKey   = "TOOLS_ADMIN_MASTER_KEY"
+	envPreviousKey = "TOOLS_ADMIN_PREVIOUS_MASTER_KEYS" // 逗号分隔
+)
+
+// ErrNoMasterKey 未配置主密钥
+var ErrNoMasterKey = errors.New("未配置主密钥，请设置环境变量 " + envMasterKey + " 或配置 security.master_key")
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring 当前主密钥和轮换前的旧密钥。加密总是使用当前密钥，解密按密文中的指纹选择密钥
+type Keyring struct {
+	current *key
+	keys    map[string]*key
+}
+
+// NewKeyring 创建密钥环，密钥为base64编码的32字节随机数
+func NewKeyring(current string, previous []string) (*Keyring, error) {
+	if current == "" {
+		return nil, ErrNoMasterKey
+	}
+	k, err := parseKey(current)
+	if err != nil {
+		return nil, fmt.Errorf("主密钥无效: %v", err)
+	}
+	ring := &Keyring{current: k, keys: map[string]*key{k.id: k}}
+	for _, text := range previous {
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		old, err := parseKey(text)
+		if err != nil {
+			return nil, fmt.Errorf("旧主密钥无效: %v", err)
+		}
+		if _, ok := ring.keys[old.id]; !ok {
+			ring.keys[old.id] = old
+		}
+	}
+	return ring, nil
+}
+
+func parseKey(text string) (*key, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("需为base64编码: %v", err)
+	}
+	if len(raw) != keySize {
+		return nil, fmt.Errorf("长度需为%d字节，实际为%d字节", keySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// Encrypt 使用当前主密钥加密
+func (r *Keyring) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, r.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := r.current.aead.Seal(nonce, nonce, []byte(plaintext), []byte(r.current.id))
+	return prefix + r.current.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密。未加密的历史数据原样返回，由密钥轮换工具统一加密
+func (r *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, data, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", fmt.Errorf("密文格式错误")
+	}
+	k, ok := r.keys[id]
+	if !ok {
+		return "", fmt.Errorf("找不到密钥 %s，轮换后请将旧密钥加入 %s", id, envPreviousKey)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %v", err)
+	}
+	nonceSize := k.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("密文格式错误")
+	}
+	plaintext, err := k.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 是否需要用当前主密钥重新加密：未加密或由旧密钥加密
+func (r *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+r.current.id+":")
+}
+
+// IsEncrypted 是否为加密后的值
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+var (
+	defaultOnce sync.Once
+	defaultRing *Keyring
+	defaultErr  error
+)
+
+// Default 返回根据环境变量和配置文件创建的密钥环
+func Default() (*Keyring, error) {
+	defaultOnce.Do(func() {
+		cfg := config.Config.Security
+		current := cfg.MasterKey
+		if env := os.Getenv(envMasterKey); env != "" {
+			current = env
+		}
+		previous := cfg.PreviousMasterKeys
+		if env := os.Getenv(envPreviousKey); env != "" {
+			previous = strings.Split(env, ",")
+		}
+		defaultRing, defaultErr = NewKeyring(current, previous)
+	})
+	return defaultRing, defaultErr
+}
+
+// Encrypt 使用默认密钥环加密
+func Encrypt(plaintext string) (string, error) {
+	ring, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return ring.Encrypt(plaintext)
+}
+
+// Decrypt 使用默认密钥环解密，未加密的值不需要密钥
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	ring, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return ring.Decrypt(value)
+}
+
+// GenerateKey 生成新的base64编码主密钥
+func GenerateKey() (string, error) {
+	raw := make([]byte, keySize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,269 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect SQL方言，取值与数据库连接的类型一致
+type Dialect string
+
+const (
+	MySQL      Dialect = "mysql"
+	PostgreSQL Dialect = "postgresql"
+)
+
+// TokenKind 词法单元类型
+type TokenKind int
+
+const (
+	Word        TokenKind = iota // 关键字或未加引号的标识符
+	QuotedIdent                  // 加引号的标识符
+	String                       // 字符串常量
+	Number                       // 数值常量
+	Param                        // 参数占位符 ? 或 $1
+	Punct                        // 标点和运算符
+)
+
+// Token 词法单元
+type Token struct {
+	Kind        TokenKind
+	Text        string // 原文
+	Value       string // Word为大写形式，QuotedIdent和String为去掉引号后的内容
+	Pos         int    // 在原文中的起始位置
+	End         int    // 在原文中的结束位置
+	SpaceBefore bool   // 与前一个词法单元之间有空白或注释
+}
+
+// 多字符运算符，按长度从长到短匹配
+var operators = []string{"<=>", "->>", "<=", ">=", "<>", "!=", "::", "||", "&&", ":=", "->", "<<", ">>"}
+
+// Tokenize 将SQL拆分为词法单元，注释被丢弃。
+// MySQL中 # 和 "-- " 开始行注释、双引号为字符串、反引号为标识符；
+// PostgreSQL中 -- 开始行注释、双引号为标识符，支持 E'...' 转义字符串和 $tag$ 字符串
+func Tokenize(sql string, dialect Dialect) ([]Token, error) {
+	var tokens []Token
+	space := false
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			space = true
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(sql[i:], "--") && (dialect != MySQL || i+2 >= len(sql) || isSpace(sql[i+2])):
+			i = skipLine(sql, i)
+			space = true
+			continue
+		case c == '#' && dialect == MySQL:
+			i = skipLine(sql, i)
+			space = true
+			continue
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end, err := skipBlockComment(sql, i, dialect == PostgreSQL)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			space = true
+			continue
+		}
+
+		tok := Token{Pos: i, SpaceBefore: space}
+		space = false
+		var err error
+		switch {
+		case c == '\'':
+			tok.Kind = String
+			tok.Value, i, err = readQuoted(sql, i, '\'', dialect == MySQL)
+		case c == '"' && dialect == MySQL:
+			tok.Kind = String
+			tok.Value, i, err = readQuoted(sql, i, '"', true)
+		case c == '"':
+			tok.Kind = QuotedIdent
+			tok.Value, i, err = readQuoted(sql, i, '"', false)
+		case c == '`' && dialect == MySQL:
+			tok.Kind = QuotedIdent
+			tok.Value, i, err = readQuoted(sql, i, '`', false)
+		case (c == 'E' || c == 'e') && dialect == PostgreSQL && i+1 < len(sql) && sql[i+1] == '\'':
+			tok.Kind = String
+			tok.Value, i, err = readQuoted(sql, i+1, '\'', true)
+		case (c == 'B' || c == 'b' || c == 'X' || c == 'x' || c == 'N' || c == 'n') && i+1 < len(sql) && sql[i+1] == '\'':
+			tok.Kind = String
+			tok.Value, i, err = readQuoted(sql, i+1, '\'', false)
+		case c == '$' && dialect == PostgreSQL && i+1 < len(sql) && isDigit(sql[i+1]):
+			tok.Kind = Param
+			i++
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+		case c == '$' && dialect == PostgreSQL && dollarTag(sql, i) != "":
+			tok.Kind = String
+			tok.Value, i, err = readDollarQuoted(sql, i)
+		case c == '?':
+			tok.Kind = Param
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			tok.Kind = Number
+			i = readNumber(sql, i)
+		case isWordStart(c):
+			tok.Kind = Word
+			for i < len(sql) && isWordChar(sql[i]) {
+				i++
+			}
+			tok.Value = strings.ToUpper(sql[tok.Pos:i])
+		default:
+			tok.Kind = Punct
+			i++
+			for _, op := range operators {
+				if strings.HasPrefix(sql[tok.Pos:], op) {
+					i = tok.Pos + len(op)
+					break
+				}
+			}
+			tok.Value = sql[tok.Pos:i]
+		}
+		if err != nil {
+			return nil, err
+		}
+		tok.End = i
+		tok.Text = sql[tok.Pos:i]
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+// Normalize 去掉注释并将空白压缩为一个空格，字符串和标识符内的内容保持不变
+func Normalize(sql string, dialect Dialect) (string, error) {
+	tokens, err := Tokenize(sql, dialect)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && tok.SpaceBefore {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok.Text)
+	}
+	return b.String(), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWordStart 标识符的首字符，非ASCII字符按字母处理
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
+
+func skipLine(sql string, i int) int {
+	if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(sql)
+}
+
+// skipBlockComment 跳过块注释，PostgreSQL的块注释可以嵌套
+func skipBlockComment(sql string, start int, nested bool) (int, error) {
+	depth := 0
+	for i := start; i+1 < len(sql); {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			i += 2
+		case sql[i] == '*' && sql[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, nil
+			}
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("注释未结束, 位置: %d", start)
+}
+
+// readQuoted 读取引号包围的内容，连续两个引号表示引号本身，backslash为true时反斜杠转义下一个字符
+func readQuoted(sql string, start int, quote byte, backslash bool) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case backslash && c == '\\' && i+1 < len(sql):
+			i++
+			b.WriteByte(sql[i])
+		case c == quote && i+1 < len(sql) && sql[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case c == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("引号未闭合, 位置: %d", start)
+}
+
+// dollarTag 返回PostgreSQL $tag$ 字符串的起始标记，不是时返回空
+func dollarTag(sql string, start int) string {
+	for i := start + 1; i < len(sql); i++ {
+		c := sql[i]
+		if c == '$' {
+			return sql[start : i+1]
+		}
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80 || (i > start+1 && isDigit(c))) {
+			return ""
+		}
+	}
+	return ""
+}
+
+func readDollarQuoted(sql string, start int) (string, int, error) {
+	tag := dollarTag(sql, start)
+	body := start + len(tag)
+	end := strings.Index(sql[body:], tag)
+	if end < 0 {
+		return "", 0, fmt.Errorf("字符串 %s 未结束, 位置: %d", tag, start)
+	}
+	return sql[body : body+end], body + end + len(tag), nil
+}
+
+func readNumber(sql string, i int) int {
+	if strings.HasPrefix(sql[i:], "0x") || strings.HasPrefix(sql[i:], "0X") {
+		i += 2
+		for i < len(sql) && strings.IndexByte("0123456789abcdefABCDEF", sql[i]) >= 0 {
+			i++
+		}
+		return i
+	}
+	for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+		i++
+	}
+	if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+		j := i + 1
+		if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+			j++
+		}
+		if j < len(sql) && isDigit(sql[j]) {
+			i = j
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
@@ -0,0 +1,470 @@
+package sqlparser
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	_ "github.com/pingcap/tidb/pkg/parser/test_driver" // 提供常量表达式的实现
+)
+
+// mysqlParsers TiDB的解析器不能并发使用，复用已创建的解析器
+var mysqlParsers = sync.Pool{New: func() interface{} { return parser.New() }}
+
+// parseMySQL 使用TiDB的MySQL语法解析器解析语句
+func parseMySQL(sql string) ([]*Statement, error) {
+	p := mysqlParsers.Get().(*parser.Parser)
+	defer mysqlParsers.Put(p)
+
+	nodes, _, err := p.Parse(sql, "", "")
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]*Statement, 0, len(nodes))
+	for _, node := range nodes {
+		statements = append(statements, mysqlStatement(node))
+	}
+	return statements, nil
+}
+
+// mysqlStatement 分析一条语句
+func mysqlStatement(node ast.StmtNode) *Statement {
+	// 语句原文包含结尾的分号，与PostgreSQL保持一致去掉
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(node.Text()), ";"))
+	typ, verb := mysqlKind(node, text)
+	c := newCollector(text, typ, verb)
+
+	// CTE名称在引用之后才可能被访问到，先收集
+	node.Accept(&mysqlCTEVisitor{ctes: c.ctes})
+	v := &mysqlVisitor{c: c, aliases: make(map[string]string), skip: make(map[*ast.TableName]bool)}
+	node.Accept(v)
+	mysqlObjectWrites(c, node, verb)
+
+	switch n := node.(type) {
+	case *ast.SelectStmt:
+		c.stmt.HasWhere = n.Where != nil
+		c.stmt.HasLimit = n.Limit != nil
+	case *ast.SetOprStmt:
+		c.stmt.HasLimit = n.Limit != nil
+		c.stmt.HasWhere = n.SelectList != nil && len(n.SelectList.Selects) > 0
+		if n.SelectList != nil {
+			for _, sel := range n.SelectList.Selects {
+				if s, ok := sel.(*ast.SelectStmt); !ok || s.Where == nil {
+					c.stmt.HasWhere = false
+				}
+			}
+		}
+	case *ast.UpdateStmt:
+		c.stmt.HasWhere = n.Where != nil
+		c.stmt.HasLimit = n.Limit != nil
+	case *ast.DeleteStmt:
+		c.stmt.HasWhere = n.Where != nil
+		c.stmt.HasLimit = n.Limit != nil
+	case *ast.ExplainStmt:
+		// EXPLAIN ANALYZE 会实际执行语句，其他EXPLAIN不执行
+		if !n.Analyze {
+			c.stmt.Writes = nil
+			return c.stmt
+		}
+		if inner, ok := n.Stmt.(ast.StmtNode); ok {
+			innerType, _ := mysqlKind(inner, "")
+			c.setType(innerType)
+		}
+	}
+	return c.finish()
+}
+
+// mysqlKind 返回语句类型和动词
+func mysqlKind(node ast.StmtNode, text string) (StatementType, string) {
+	switch n := node.(type) {
+	case *ast.SelectStmt, *ast.SetOprStmt:
+		return TypeRead, "SELECT"
+	case *ast.ShowStmt:
+		return TypeRead, "SHOW"
+	case *ast.ExplainStmt, *ast.ExplainForStmt:
+		return TypeRead, firstWord(text, MySQL)
+	case *ast.HelpStmt:
+		return TypeRead, "HELP"
+
+	case *ast.InsertStmt:
+		if n.IsReplace {
+			return TypeDML, "REPLACE"
+		}
+		return TypeDML, "INSERT"
+	case *ast.UpdateStmt:
+		return TypeDML, "UPDATE"
+	case *ast.DeleteStmt:
+		return TypeDML, "DELETE"
+	case *ast.NonTransactionalDMLStmt:
+		typ, _ := mysqlKind(n.DMLStmt, "")
+		return typ, "BATCH"
+	case *ast.LoadDataStmt:
+		return TypeDML, "LOAD DATA"
+	case *ast.ImportIntoStmt:
+		return TypeDML, "IMPORT"
+
+	case *ast.CreateTableStmt:
+		return TypeDDL, "CREATE TABLE"
+	case *ast.AlterTableStmt:
+		return TypeDDL, "ALTER TABLE"
+	case *ast.DropTableStmt:
+		if n.IsView {
+			return TypeDDL, "DROP VIEW"
+		}
+		return TypeDDL, "DROP TABLE"
+	case *ast.TruncateTableStmt:
+		return TypeDDL, "TRUNCATE"
+	case *ast.RenameTableStmt:
+		return TypeDDL, "RENAME TABLE"
+	case *ast.CreateViewStmt:
+		return TypeDDL, "CREATE VIEW"
+	case *ast.CreateIndexStmt:
+		return TypeDDL, "CREATE INDEX"
+	case *ast.DropIndexStmt:
+		return TypeDDL, "DROP INDEX"
+	case *ast.CreateDatabaseStmt:
+		return TypeDDL, "CREATE DATABASE"
+	case *ast.AlterDatabaseStmt:
+		return TypeDDL, "ALTER DATABASE"
+	case *ast.DropDatabaseStmt:
+		return TypeDDL, "DROP DATABASE"
+	case *ast.CreateSequenceStmt:
+		return TypeDDL, "CREATE SEQUENCE"
+	case *ast.DropSequenceStmt:
+		return TypeDDL, "DROP SEQUENCE"
+	case *ast.DropProcedureStmt:
+		return TypeDDL, "DROP PROCEDURE"
+	case *ast.OptimizeTableStmt:
+		return TypeOther, "OPTIMIZE"
+
+	case *ast.CreateUserStmt:
+		if n.IsCreateRole {
+			return TypeDCL, "CREATE ROLE"
+		}
+		return TypeDCL, "CREATE USER"
+	case *ast.AlterUserStmt:
+		return TypeDCL, "ALTER USER"
+	case *ast.DropUserStmt:
+		if n.IsDropRole {
+			return TypeDCL, "DROP ROLE"
+		}
+		return TypeDCL, "DROP USER"
+	case *ast.RenameUserStmt:
+		return TypeDCL, "RENAME USER"
+	case *ast.GrantStmt, *ast.GrantRoleStmt, *ast.GrantProxyStmt:
+		return TypeDCL, "GRANT"
+	case *ast.RevokeStmt, *ast.RevokeRoleStmt:
+		return TypeDCL, "REVOKE"
+	case *ast.SetPwdStmt:
+		return TypeDCL, "SET PASSWORD"
+	case *ast.SetDefaultRoleStmt:
+		return TypeDCL, "SET DEFAULT ROLE"
+
+	case *ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt, *ast.SavepointStmt, *ast.ReleaseSavepointStmt:
+		return TypeTCL, firstWord(text, MySQL)
+
+	case *ast.SetStmt:
+		for _, variable := range n.Variables {
+			if variable.IsGlobal {
+				return TypeOther, "SET GLOBAL"
+			}
+		}
+		return TypeOther, "SET"
+	case *ast.SetRoleStmt:
+		return TypeOther, "SET ROLE"
+	case *ast.LoadStatsStmt:
+		return TypeOther, "LOAD STATS"
+	case *ast.UseStmt:
+		// 切换库会改变连接池中连接的默认库，影响后续使用该连接的查询，不按只读处理
+		return TypeOther, "USE"
+	}
+
+	if _, ok := node.(ast.DDLNode); ok {
+		return TypeDDL, firstWord(text, MySQL)
+	}
+	// KILL、FLUSH、CALL、DO、PREPARE、EXECUTE、LOCK TABLES 等，动词即为第一个关键字
+	return TypeOther, firstWord(text, MySQL)
+}
+
+// mysqlObjectWrites 记录结构变更修改的对象
+func mysqlObjectWrites(c *collector, node ast.StmtNode, verb string) {
+	table := func(t *ast.TableName) string {
+		if t == nil {
+			return ""
+		}
+		return qualify(t.Schema.O, t.Name.O)
+	}
+	switch n := node.(type) {
+	case *ast.CreateTableStmt:
+		c.addWrite(verb, table(n.Table), true)
+	case *ast.AlterTableStmt:
+		c.addWrite(verb, table(n.Table), true)
+	case *ast.DropTableStmt:
+		for _, t := range n.Tables {
+			c.addWrite(verb, table(t), true)
+		}
+	case *ast.TruncateTableStmt:
+		c.addWrite(verb, table(n.Table), true)
+	case *ast.RenameTableStmt:
+		for _, t := range n.TableToTables {
+			c.addWrite(verb, table(t.OldTable), true)
+		}
+	case *ast.CreateViewStmt:
+		c.addWrite(verb, table(n.ViewName), true)
+	case *ast.CreateIndexStmt:
+		c.addWrite(verb, table(n.Table), true)
+	case *ast.DropIndexStmt:
+		c.addWrite(verb, table(n.Table), true)
+	case *ast.CreateDatabaseStmt:
+		c.addWrite(verb, n.Name.O, true)
+	case *ast.AlterDatabaseStmt:
+		c.addWrite(verb, n.Name.O, true)
+	case *ast.DropDatabaseStmt:
+		c.addWrite(verb, n.Name.O, true)
+	case *ast.CreateSequenceStmt:
+		c.addWrite(verb, table(n.Name), true)
+	case *ast.DropSequenceStmt:
+		for _, t := range n.Sequences {
+			c.addWrite(verb, table(t), true)
+		}
+	}
+}
+
+// mysqlCTEVisitor 收集CTE名称
+type mysqlCTEVisitor struct {
+	ctes map[string]bool
+}
+
+func (v *mysqlCTEVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	if cte, ok := n.(*ast.CommonTableExpression); ok {
+		v.ctes[cte.Name.L] = true
+	}
+	return n, false
+}
+
+func (v *mysqlCTEVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// mysqlVisitor 收集表、函数和数据修改
+type mysqlVisitor struct {
+	c       *collector
+	aliases map[string]string       // 表别名(小写)到表名
+	skip    map[*ast.TableName]bool // 不是表引用的名称，如多表删除的目标别名、重命名后的表名
+}
+
+func (v *mysqlVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.TableName:
+		if !v.skip[n] {
+			v.c.addTable(n.Schema.O, n.Name.O)
+		}
+	case *ast.TableSource:
+		if t, ok := n.Source.(*ast.TableName); ok && n.AsName.L != "" {
+			v.aliases[n.AsName.L] = qualify(t.Schema.O, t.Name.O)
+		}
+	case *ast.FuncCallExpr:
+		v.c.addFunction(n.FnName.O)
+	case *ast.DeleteStmt:
+		if n.IsMultiTable && n.Tables != nil {
+			for _, t := range n.Tables.Tables {
+				v.skip[t] = true
+			}
+		}
+	case *ast.RenameTableStmt:
+		for _, t := range n.TableToTables {
+			v.skip[t.NewTable] = true
+		}
+	}
+	return n, false
+}
+
+// Leave 在子节点访问完成后记录修改，此时表别名已经收集完整
+func (v *mysqlVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.SelectStmt:
+		if n.Fields != nil {
+			for _, field := range n.Fields.Fields {
+				if field.WildCard != nil {
+					v.c.stmt.SelectAll = true
+				}
+			}
+		}
+		if n.LockInfo != nil && n.LockInfo.LockType != ast.SelectLockNone {
+			v.c.stmt.Locking = true
+		}
+		if n.SelectIntoOpt != nil && (n.SelectIntoOpt.Tp == ast.SelectIntoOutfile || n.SelectIntoOpt.Tp == ast.SelectIntoDumpfile) {
+			v.c.stmt.IntoFile = true
+		}
+		v.checkTautology(n.Where)
+	case *ast.InsertStmt:
+		verb := "INSERT"
+		if n.IsReplace {
+			verb = "REPLACE"
+		}
+		if n.Table != nil {
+			for _, t := range joinTables(n.Table.TableRefs) {
+				v.c.addWrite(verb, t, true)
+			}
+		}
+	case *ast.UpdateStmt:
+		for _, t := range v.updateTargets(n) {
+			v.c.addWrite("UPDATE", t, n.Where != nil)
+		}
+		v.checkTautology(n.Where)
+	case *ast.DeleteStmt:
+		var targets []string
+		if n.IsMultiTable && n.Tables != nil {
+			for _, t := range n.Tables.Tables {
+				targets = append(targets, v.resolve(t))
+			}
+		} else if n.TableRefs != nil {
+			targets = joinTables(n.TableRefs.TableRefs)
+		}
+		for _, t := range targets {
+			v.c.addWrite("DELETE", t, n.Where != nil)
+		}
+		v.checkTautology(n.Where)
+	case *ast.LoadDataStmt:
+		if n.Table != nil {
+			v.c.addWrite("LOAD DATA", qualify(n.Table.Schema.O, n.Table.Name.O), true)
+		}
+	}
+	return n, true
+}
+
+// resolve 将别名解析为表名
+func (v *mysqlVisitor) resolve(t *ast.TableName) string {
+	if t.Schema.L == "" {
+		if table, ok := v.aliases[t.Name.L]; ok {
+			return table
+		}
+	}
+	return qualify(t.Schema.O, t.Name.O)
+}
+
+// updateTargets 返回UPDATE修改的表。多表更新时为SET中出现的表，无法确定时为所有表
+func (v *mysqlVisitor) updateTargets(n *ast.UpdateStmt) []string {
+	if n.TableRefs == nil {
+		return nil
+	}
+	tables := joinTables(n.TableRefs.TableRefs)
+	if len(tables) <= 1 {
+		return tables
+	}
+	var targets []string
+	seen := make(map[string]bool)
+	for _, assignment := range n.List {
+		col := assignment.Column
+		if col == nil || col.Table.L == "" {
+			return tables
+		}
+		table := v.resolve(&ast.TableName{Schema: col.Schema, Name: col.Table})
+		if !seen[strings.ToLower(table)] {
+			seen[strings.ToLower(table)] = true
+			targets = append(targets, table)
+		}
+	}
+	return targets
+}
+
+func (v *mysqlVisitor) checkTautology(where ast.ExprNode) {
+	if where == nil {
+		return
+	}
+	if isTrueValue(where) {
+		v.c.stmt.Tautology = true
+		return
+	}
+	t := &tautologyVisitor{}
+	where.Accept(t)
+	if t.found {
+		v.c.stmt.Tautology = true
+	}
+}
+
+// joinTables 返回连接中的表，子查询不计入
+func joinTables(node ast.ResultSetNode) []string {
+	switch n := node.(type) {
+	case *ast.Join:
+		tables := joinTables(n.Left)
+		if n.Right != nil {
+			tables = append(tables, joinTables(n.Right)...)
+		}
+		return tables
+	case *ast.TableSource:
+		return joinTables(n.Source)
+	case *ast.TableName:
+		return []string{qualify(n.Schema.O, n.Name.O)}
+	}
+	return nil
+}
+
+// tautologyVisitor 查找两边相同的等值比较和 OR TRUE，子查询的条件单独检查
+type tautologyVisitor struct {
+	found bool
+}
+
+func (t *tautologyVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.SubqueryExpr:
+		return n, true
+	case *ast.BinaryOperationExpr:
+		switch n.Op {
+		case opcode.EQ, opcode.NullEQ:
+			if sameOperand(n.L, n.R) {
+				t.found = true
+			}
+		case opcode.LogicOr:
+			if isTrueValue(n.L) || isTrueValue(n.R) {
+				t.found = true
+			}
+		}
+	}
+	return n, false
+}
+
+func (t *tautologyVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+func unparen(expr ast.ExprNode) ast.ExprNode {
+	for {
+		p, ok := expr.(*ast.ParenthesesExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
+
+// sameOperand 是否为相同的常量或同一列
+func sameOperand(l, r ast.ExprNode) bool {
+	switch l := unparen(l).(type) {
+	case ast.ValueExpr:
+		rv, ok := unparen(r).(ast.ValueExpr)
+		return ok && l.GetValue() != nil && reflect.DeepEqual(l.GetValue(), rv.GetValue())
+	case *ast.ColumnNameExpr:
+		rc, ok := unparen(r).(*ast.ColumnNameExpr)
+		return ok && strings.EqualFold(l.Name.String(), rc.Name.String())
+	}
+	return false
+}
+
+// isTrueValue 是否为非零的整数常量，MySQL中 TRUE 即为 1
+func isTrueValue(expr ast.ExprNode) bool {
+	v, ok := unparen(expr).(ast.ValueExpr)
+	if !ok {
+		return false
+	}
+	switch value := v.GetValue().(type) {
+	case int64:
+		return value != 0
+	case uint64:
+		return value != 0
+	}
+	return false
+}
@@ -0,0 +1,416 @@
+package sqlparser
+
+import (
+	"strconv"
+	"strings"
+
+	pg "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// parsePostgres 使用libpg_query解析语句，语法与PostgreSQL服务端一致
+func parsePostgres(sql string) ([]*Statement, error) {
+	tree, err := pg.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]*Statement, 0, len(tree.Stmts))
+	for _, raw := range tree.Stmts {
+		start := int(raw.StmtLocation)
+		end := len(sql)
+		if raw.StmtLen > 0 {
+			end = start + int(raw.StmtLen)
+		}
+		statements = append(statements, postgresStatement(raw.Stmt, strings.TrimSpace(sql[start:end])))
+	}
+	return statements, nil
+}
+
+// postgresStatement 分析一条语句
+func postgresStatement(node *pg.Node, text string) *Statement {
+	typ, verb := postgresKind(node, text)
+	c := newCollector(text, typ, verb)
+
+	// CTE名称在引用之后才可能被访问到，先收集
+	walkNode(node.ProtoReflect(), func(m proto.Message) bool {
+		if cte, ok := m.(*pg.CommonTableExpr); ok {
+			c.ctes[strings.ToLower(cte.Ctename)] = true
+		}
+		return true
+	})
+	walkNode(node.ProtoReflect(), func(m proto.Message) bool {
+		postgresVisit(c, m)
+		return true
+	})
+	postgresObjectWrites(c, node, verb)
+
+	switch n := node.Node.(type) {
+	case *pg.Node_SelectStmt:
+		c.stmt.HasWhere = selectHasWhere(n.SelectStmt)
+		c.stmt.HasLimit = n.SelectStmt.LimitCount != nil
+	case *pg.Node_UpdateStmt:
+		c.stmt.HasWhere = n.UpdateStmt.WhereClause != nil
+	case *pg.Node_DeleteStmt:
+		c.stmt.HasWhere = n.DeleteStmt.WhereClause != nil
+	case *pg.Node_ExplainStmt:
+		// EXPLAIN ANALYZE 会实际执行语句，其他EXPLAIN不执行
+		if !explainAnalyze(n.ExplainStmt) {
+			c.stmt.Writes = nil
+			return c.stmt
+		}
+		innerType, _ := postgresKind(n.ExplainStmt.Query, "")
+		c.setType(innerType)
+	}
+	return c.finish()
+}
+
+// postgresKind 返回语句类型和动词
+func postgresKind(node *pg.Node, text string) (StatementType, string) {
+	switch n := node.Node.(type) {
+	case *pg.Node_SelectStmt:
+		return TypeRead, "SELECT"
+	case *pg.Node_ExplainStmt:
+		return TypeRead, "EXPLAIN"
+	case *pg.Node_VariableShowStmt:
+		return TypeRead, "SHOW"
+
+	case *pg.Node_InsertStmt:
+		return TypeDML, "INSERT"
+	case *pg.Node_UpdateStmt:
+		return TypeDML, "UPDATE"
+	case *pg.Node_DeleteStmt:
+		return TypeDML, "DELETE"
+	case *pg.Node_MergeStmt:
+		return TypeDML, "MERGE"
+
+	case *pg.Node_CreateStmt:
+		return TypeDDL, "CREATE TABLE"
+	case *pg.Node_CreateTableAsStmt:
+		if n.CreateTableAsStmt.Objtype == pg.ObjectType_OBJECT_MATVIEW {
+			return TypeDDL, "CREATE MATERIALIZED VIEW"
+		}
+		return TypeDDL, "CREATE TABLE"
+	case *pg.Node_ViewStmt:
+		return TypeDDL, "CREATE VIEW"
+	case *pg.Node_IndexStmt:
+		return TypeDDL, "CREATE INDEX"
+	case *pg.Node_AlterTableStmt:
+		return TypeDDL, "ALTER " + objectTypeName(n.AlterTableStmt.Objtype)
+	case *pg.Node_DropStmt:
+		return TypeDDL, "DROP " + objectTypeName(n.DropStmt.RemoveType)
+	case *pg.Node_TruncateStmt:
+		return TypeDDL, "TRUNCATE"
+	case *pg.Node_RenameStmt:
+		return TypeDDL, "RENAME " + objectTypeName(n.RenameStmt.RenameType)
+	case *pg.Node_CreatedbStmt:
+		return TypeDDL, "CREATE DATABASE"
+	case *pg.Node_DropdbStmt:
+		return TypeDDL, "DROP DATABASE"
+	case *pg.Node_CreateSchemaStmt:
+		return TypeDDL, "CREATE SCHEMA"
+	case *pg.Node_CommentStmt:
+		return TypeDDL, "COMMENT ON"
+
+	case *pg.Node_CreateRoleStmt:
+		return TypeDCL, "CREATE " + strings.TrimPrefix(n.CreateRoleStmt.StmtType.String(), "ROLESTMT_")
+	case *pg.Node_AlterRoleStmt, *pg.Node_AlterRoleSetStmt:
+		return TypeDCL, "ALTER ROLE"
+	case *pg.Node_DropRoleStmt:
+		return TypeDCL, "DROP ROLE"
+	case *pg.Node_GrantStmt:
+		return TypeDCL, grantVerb(n.GrantStmt.IsGrant)
+	case *pg.Node_GrantRoleStmt:
+		return TypeDCL, grantVerb(n.GrantRoleStmt.IsGrant)
+	case *pg.Node_AlterDefaultPrivilegesStmt:
+		return TypeDCL, "ALTER DEFAULT PRIVILEGES"
+	case *pg.Node_ReassignOwnedStmt:
+		return TypeDCL, "REASSIGN OWNED"
+	case *pg.Node_DropOwnedStmt:
+		return TypeDCL, "DROP OWNED"
+
+	case *pg.Node_TransactionStmt:
+		return TypeTCL, firstWord(text, PostgreSQL)
+
+	case *pg.Node_VariableSetStmt:
+		if name := n.VariableSetStmt.Name; name == "role" || name == "session_authorization" {
+			return TypeOther, "SET ROLE"
+		}
+		return TypeOther, "SET"
+	case *pg.Node_AlterSystemStmt:
+		return TypeOther, "ALTER SYSTEM"
+	case *pg.Node_VacuumStmt:
+		if n.VacuumStmt.IsVacuumcmd {
+			return TypeOther, "VACUUM"
+		}
+		return TypeOther, "ANALYZE"
+	case *pg.Node_CheckPointStmt:
+		return TypeOther, "CHECKPOINT"
+	}
+
+	// 其他CREATE/ALTER/DROP语句按结构变更处理，如函数、触发器、扩展
+	name := nodeName(node)
+	if strings.HasPrefix(name, "Create") || strings.HasPrefix(name, "Alter") || strings.HasPrefix(name, "Drop") {
+		return TypeDDL, firstWord(text, PostgreSQL)
+	}
+	// COPY、CALL、DO、LOCK、LOAD、PREPARE、EXECUTE 等，动词即为第一个关键字
+	return TypeOther, firstWord(text, PostgreSQL)
+}
+
+// postgresVisit 收集一个节点中的表、函数和数据修改
+func postgresVisit(c *collector, m proto.Message) {
+	switch n := m.(type) {
+	case *pg.RangeVar:
+		c.addTable(n.Schemaname, n.Relname)
+	case *pg.FuncCall:
+		// 只取函数名，pg_catalog.set_config 与 set_config 相同
+		if len(n.Funcname) > 0 {
+			c.addFunction(n.Funcname[len(n.Funcname)-1].GetString_().GetSval())
+		}
+	case *pg.SelectStmt:
+		for _, target := range n.TargetList {
+			if isStarRef(target.GetResTarget().GetVal()) {
+				c.stmt.SelectAll = true
+			}
+		}
+		if len(n.LockingClause) > 0 {
+			c.stmt.Locking = true
+		}
+		if n.IntoClause != nil {
+			c.addWrite("SELECT INTO", rangeVarName(n.IntoClause.Rel), true)
+		}
+		checkPostgresTautology(c, n.WhereClause)
+	case *pg.InsertStmt:
+		c.addWrite("INSERT", rangeVarName(n.Relation), true)
+	case *pg.UpdateStmt:
+		c.addWrite("UPDATE", rangeVarName(n.Relation), n.WhereClause != nil)
+		checkPostgresTautology(c, n.WhereClause)
+	case *pg.DeleteStmt:
+		c.addWrite("DELETE", rangeVarName(n.Relation), n.WhereClause != nil)
+		checkPostgresTautology(c, n.WhereClause)
+	case *pg.MergeStmt:
+		c.addWrite("MERGE", rangeVarName(n.Relation), true)
+	case *pg.CopyStmt:
+		switch {
+		case n.IsFrom:
+			c.addWrite("COPY", rangeVarName(n.Relation), true)
+		case n.Filename != "" || n.IsProgram:
+			c.stmt.IntoFile = true
+		}
+	}
+}
+
+// postgresObjectWrites 记录结构变更修改的对象
+func postgresObjectWrites(c *collector, node *pg.Node, verb string) {
+	switch n := node.Node.(type) {
+	case *pg.Node_CreateStmt:
+		c.addWrite(verb, rangeVarName(n.CreateStmt.Relation), true)
+	case *pg.Node_CreateTableAsStmt:
+		if n.CreateTableAsStmt.Into != nil {
+			c.addWrite(verb, rangeVarName(n.CreateTableAsStmt.Into.Rel), true)
+		}
+	case *pg.Node_ViewStmt:
+		c.addWrite(verb, rangeVarName(n.ViewStmt.View), true)
+	case *pg.Node_IndexStmt:
+		c.addWrite(verb, rangeVarName(n.IndexStmt.Relation), true)
+	case *pg.Node_AlterTableStmt:
+		c.addWrite(verb, rangeVarName(n.AlterTableStmt.Relation), true)
+	case *pg.Node_DropStmt:
+		for _, object := range n.DropStmt.Objects {
+			c.addWrite(verb, objectName(object), true)
+		}
+	case *pg.Node_TruncateStmt:
+		for _, rel := range n.TruncateStmt.Relations {
+			c.addWrite(verb, rangeVarName(rel.GetRangeVar()), true)
+		}
+	case *pg.Node_RenameStmt:
+		if n.RenameStmt.Relation != nil {
+			c.addWrite(verb, rangeVarName(n.RenameStmt.Relation), true)
+		} else {
+			c.addWrite(verb, objectName(n.RenameStmt.Object), true)
+		}
+	case *pg.Node_CreatedbStmt:
+		c.addWrite(verb, n.CreatedbStmt.Dbname, true)
+	case *pg.Node_DropdbStmt:
+		c.addWrite(verb, n.DropdbStmt.Dbname, true)
+	case *pg.Node_CreateSchemaStmt:
+		c.addWrite(verb, n.CreateSchemaStmt.Schemaname, true)
+	}
+}
+
+// walkNode 深度优先遍历语法树，visit返回false时不再访问子节点
+func walkNode(m protoreflect.Message, visit func(proto.Message) bool) {
+	if !m.IsValid() || !visit(m.Interface()) {
+		return
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsMap() {
+			return true
+		}
+		if fd.IsList() {
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				walkNode(list.Get(i).Message(), visit)
+			}
+			return true
+		}
+		walkNode(v.Message(), visit)
+		return true
+	})
+}
+
+// nodeName 返回节点的类型名，如 CreateFunctionStmt
+func nodeName(node *pg.Node) string {
+	m := node.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("node"))
+	if fd == nil {
+		return ""
+	}
+	return string(fd.Message().Name())
+}
+
+// objectTypeName 返回对象类型的名称，如 OBJECT_MATVIEW 为 MATERIALIZED VIEW
+func objectTypeName(t pg.ObjectType) string {
+	if t == pg.ObjectType_OBJECT_MATVIEW {
+		return "MATERIALIZED VIEW"
+	}
+	return strings.ReplaceAll(strings.TrimPrefix(t.String(), "OBJECT_"), "_", " ")
+}
+
+func grantVerb(isGrant bool) string {
+	if isGrant {
+		return "GRANT"
+	}
+	return "REVOKE"
+}
+
+func rangeVarName(rv *pg.RangeVar) string {
+	if rv == nil {
+		return ""
+	}
+	return qualify(rv.Schemaname, rv.Relname)
+}
+
+// objectName 返回DROP等语句中的对象名，限定名以 List 表示
+func objectName(node *pg.Node) string {
+	if s := node.GetString_(); s != nil {
+		return s.Sval
+	}
+	var parts []string
+	for _, item := range node.GetList().GetItems() {
+		if s := item.GetString_(); s != nil {
+			parts = append(parts, s.Sval)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// isStarRef 是否为 * 或 t.*
+func isStarRef(node *pg.Node) bool {
+	fields := node.GetColumnRef().GetFields()
+	return len(fields) > 0 && fields[len(fields)-1].GetAStar() != nil
+}
+
+// selectHasWhere 查询带WHERE条件，集合运算要求每个分支都带
+func selectHasWhere(s *pg.SelectStmt) bool {
+	if s.Op != pg.SetOperation_SETOP_NONE {
+		return s.Larg != nil && s.Rarg != nil && selectHasWhere(s.Larg) && selectHasWhere(s.Rarg)
+	}
+	return s.WhereClause != nil
+}
+
+// explainAnalyze 是否为 EXPLAIN ANALYZE 或 EXPLAIN (ANALYZE ...)
+func explainAnalyze(n *pg.ExplainStmt) bool {
+	for _, option := range n.Options {
+		if option.GetDefElem().GetDefname() == "analyze" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPostgresTautology 查找两边相同的等值比较和 OR TRUE，子查询的条件单独检查
+func checkPostgresTautology(c *collector, where *pg.Node) {
+	if where == nil {
+		return
+	}
+	if isTrueConst(where) {
+		c.stmt.Tautology = true
+		return
+	}
+	walkNode(where.ProtoReflect(), func(m proto.Message) bool {
+		switch n := m.(type) {
+		case *pg.SubLink:
+			return false
+		case *pg.A_Expr:
+			if isEqualOperator(n) && sameTerm(n.Lexpr, n.Rexpr) {
+				c.stmt.Tautology = true
+			}
+		case *pg.BoolExpr:
+			if n.Boolop == pg.BoolExprType_OR_EXPR {
+				for _, arg := range n.Args {
+					if isTrueConst(arg) {
+						c.stmt.Tautology = true
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func isEqualOperator(n *pg.A_Expr) bool {
+	switch n.Kind {
+	case pg.A_Expr_Kind_AEXPR_NOT_DISTINCT:
+		return true
+	case pg.A_Expr_Kind_AEXPR_OP:
+		return len(n.Name) == 1 && n.Name[0].GetString_().GetSval() == "="
+	}
+	return false
+}
+
+// sameTerm 是否为相同的常量或同一列
+func sameTerm(l, r *pg.Node) bool {
+	if lc, rc := l.GetAConst(), r.GetAConst(); lc != nil && rc != nil {
+		lv, lok := constText(lc)
+		rv, rok := constText(rc)
+		return lok && rok && lv == rv
+	}
+	if lc, rc := l.GetColumnRef(), r.GetColumnRef(); lc != nil && rc != nil {
+		return !isStarRef(l) && columnRefName(lc) == columnRefName(rc)
+	}
+	return false
+}
+
+// constText 返回带类型前缀的常量文本，NULL返回false
+func constText(c *pg.A_Const) (string, bool) {
+	switch {
+	case c.Isnull:
+		return "", false
+	case c.GetIval() != nil:
+		return "i" + strconv.Itoa(int(c.GetIval().Ival)), true
+	case c.GetFval() != nil:
+		return "f" + c.GetFval().Fval, true
+	case c.GetBoolval() != nil:
+		return "b" + strconv.FormatBool(c.GetBoolval().Boolval), true
+	case c.GetSval() != nil:
+		return "s" + c.GetSval().Sval, true
+	case c.GetBsval() != nil:
+		return "x" + c.GetBsval().Bsval, true
+	}
+	return "", false
+}
+
+func columnRefName(c *pg.ColumnRef) string {
+	parts := make([]string, 0, len(c.Fields))
+	for _, field := range c.Fields {
+		parts = append(parts, field.GetString_().GetSval())
+	}
+	return strings.Join(parts, ".")
+}
+
+// isTrueConst 是否为常量 TRUE
+func isTrueConst(node *pg.Node) bool {
+	c := node.GetAConst()
+	return c != nil && !c.Isnull && c.GetBoolval() != nil && c.GetBoolval().Boolval
+}
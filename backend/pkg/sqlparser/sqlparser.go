@@ -0,0 +1,150 @@
+// Package sqlparser 解析MySQL和PostgreSQL语句，识别语句类型、涉及的表和修改的表，用于SQL安全分析。
+// MySQL使用TiDB的语法解析器，PostgreSQL使用libpg_query(PostgreSQL服务端的语法解析器)，
+// 无法按语法解析的SQL返回错误
+package sqlparser
+
+import (
+	"strings"
+)
+
+// StatementType 语句类型
+type StatementType string
+
+const (
+	TypeRead  StatementType = "read"  // 查询，如 SELECT、SHOW、EXPLAIN
+	TypeDML   StatementType = "dml"   // 数据修改，如 INSERT、UPDATE、DELETE
+	TypeDDL   StatementType = "ddl"   // 结构定义，如 CREATE、ALTER、DROP、TRUNCATE
+	TypeDCL   StatementType = "dcl"   // 权限控制，如 GRANT、REVOKE、CREATE USER
+	TypeTCL   StatementType = "tcl"   // 事务控制，如 BEGIN、COMMIT
+	TypeOther StatementType = "other" // 其他，如 SET、CALL、LOAD DATA
+)
+
+// typeRank 语句类型的影响程度，包含多种操作时取影响最大的类型
+var typeRank = map[StatementType]int{
+	TypeTCL:   1,
+	TypeRead:  2,
+	TypeOther: 3,
+	TypeDML:   4,
+	TypeDDL:   5,
+	TypeDCL:   6,
+}
+
+// Write 语句对一个对象的修改
+type Write struct {
+	Verb     string `json:"verb"`     // 修改操作，如 DELETE、UPDATE、DROP TABLE
+	Object   string `json:"object"`   // 被修改的表或其他对象
+	Filtered bool   `json:"filtered"` // UPDATE/DELETE是否带WHERE条件，其他操作总为true
+}
+
+// Statement 一条语句的解析结果
+type Statement struct {
+	Text      string        // 语句原文
+	Type      StatementType // 语句类型
+	Verb      string        // 语句动词，如 SELECT、DELETE、CREATE TABLE
+	Tables    []string      // 涉及的表，不含CTE名称，按出现顺序去重
+	Writes    []Write       // 修改的对象，包含CTE和子查询中的修改
+	Functions []string      // 调用的函数(大写，不含模式名)，去重
+	HasWhere  bool          // 主语句带WHERE条件
+	HasLimit  bool          // 主语句带LIMIT
+	SelectAll bool          // 查询使用了 SELECT *
+	IntoFile  bool          // 结果写入服务器文件，如 SELECT ... INTO OUTFILE、COPY ... TO 'file'
+	Locking   bool          // 加锁读，如 FOR UPDATE、LOCK IN SHARE MODE
+	Tautology bool          // WHERE条件恒为真，如 1=1、'a'='a'、OR TRUE
+}
+
+// dmlVerbs 修改数据的操作，出现在任意位置时语句即为数据修改
+var dmlVerbs = map[string]bool{
+	"INSERT": true, "REPLACE": true, "UPDATE": true, "DELETE": true, "MERGE": true, "LOAD DATA": true, "COPY": true,
+}
+
+// Parse 按方言解析以分号分隔的一条或多条语句
+func Parse(sql string, dialect Dialect) ([]*Statement, error) {
+	if dialect == PostgreSQL {
+		return parsePostgres(sql)
+	}
+	return parseMySQL(sql)
+}
+
+// collector 收集一条语句的解析结果
+type collector struct {
+	stmt  *Statement
+	ctes  map[string]bool // CTE名称(小写)
+	seen  map[string]bool
+	funcs map[string]bool
+}
+
+func newCollector(text string, typ StatementType, verb string) *collector {
+	return &collector{
+		stmt:  &Statement{Text: text, Type: typ, Verb: verb},
+		ctes:  make(map[string]bool),
+		seen:  make(map[string]bool),
+		funcs: make(map[string]bool),
+	}
+}
+
+// setType 设置语句类型，已有影响更大的类型时保留
+func (c *collector) setType(t StatementType) {
+	if typeRank[t] > typeRank[c.stmt.Type] {
+		c.stmt.Type = t
+	}
+}
+
+// addTable 记录涉及的表，引用CTE的名称不记录
+func (c *collector) addTable(schema, name string) {
+	if name == "" || (schema == "" && c.ctes[strings.ToLower(name)]) {
+		return
+	}
+	table := qualify(schema, name)
+	key := strings.ToLower(table)
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.stmt.Tables = append(c.stmt.Tables, table)
+}
+
+func (c *collector) addWrite(verb, object string, filtered bool) {
+	if object == "" {
+		return
+	}
+	c.stmt.Writes = append(c.stmt.Writes, Write{Verb: verb, Object: object, Filtered: filtered})
+}
+
+func (c *collector) addFunction(name string) {
+	name = strings.ToUpper(name)
+	if name == "" || c.funcs[name] {
+		return
+	}
+	c.funcs[name] = true
+	c.stmt.Functions = append(c.stmt.Functions, name)
+}
+
+// finish 根据语句中的修改提升语句类型，如CTE中的DELETE使查询成为数据修改
+func (c *collector) finish() *Statement {
+	for _, w := range c.stmt.Writes {
+		switch {
+		case dmlVerbs[w.Verb]:
+			c.setType(TypeDML)
+		case w.Verb == "SELECT INTO":
+			c.setType(TypeDDL)
+		}
+	}
+	return c.stmt
+}
+
+// qualify 返回带库名或模式名的对象名
+func qualify(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+// firstWord 返回语句的第一个关键字(大写)，用于没有单独区分的语句类型
+func firstWord(text string, dialect Dialect) string {
+	tokens, err := Tokenize(text, dialect)
+	if err != nil || len(tokens) == 0 || tokens[0].Kind != Word {
+		return ""
+	}
+	return tokens[0].Value
+}
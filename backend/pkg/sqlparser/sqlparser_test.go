@@ -0,0 +1,174 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   Dialect
+		sql       string
+		typ       StatementType
+		verb      string
+		tables    []string
+		writes    []Write
+		functions []string
+		where     bool
+		locking   bool
+		tautology bool
+	}{
+		// 关键字出现在标识符和字符串中不影响分类
+		{name: "column named like keyword", dialect: MySQL, sql: "SELECT updated_set FROM t WHERE id = 1",
+			typ: TypeRead, verb: "SELECT", tables: []string{"t"}, where: true},
+		{name: "comment marker in string", dialect: MySQL, sql: "SELECT '--' AS a, '/*' FROM t WHERE note = '; DROP TABLE t'",
+			typ: TypeRead, verb: "SELECT", tables: []string{"t"}, where: true},
+		{name: "comment marker in string pg", dialect: PostgreSQL, sql: "SELECT '--', $$; DELETE FROM t$$ FROM t WHERE id = 1",
+			typ: TypeRead, verb: "SELECT", tables: []string{"t"}, where: true},
+
+		// 修改语句
+		{name: "replace into", dialect: MySQL, sql: "REPLACE INTO t (id, name) VALUES (1, 'a')",
+			typ: TypeDML, verb: "REPLACE", tables: []string{"t"}, writes: []Write{{"REPLACE", "t", true}}},
+		{name: "delete without where", dialect: MySQL, sql: "DELETE FROM db1.t",
+			typ: TypeDML, verb: "DELETE", tables: []string{"db1.t"}, writes: []Write{{"DELETE", "db1.t", false}}},
+		{name: "multi-table delete resolves alias", dialect: MySQL, sql: "DELETE a FROM t1 a JOIN t2 b ON a.id = b.id WHERE b.x = 1",
+			typ: TypeDML, verb: "DELETE", tables: []string{"t1", "t2"}, writes: []Write{{"DELETE", "t1", true}}, where: true},
+		{name: "multi-table update targets set tables", dialect: MySQL, sql: "UPDATE t1 a JOIN t2 b ON a.id = b.id SET a.x = b.x",
+			typ: TypeDML, verb: "UPDATE", tables: []string{"t1", "t2"}, writes: []Write{{"UPDATE", "t1", false}}},
+		{name: "delete in cte mysql", dialect: MySQL, sql: "WITH x AS (SELECT id FROM t2) DELETE FROM t WHERE id IN (SELECT id FROM x)",
+			typ: TypeDML, verb: "DELETE", tables: []string{"t2", "t"}, writes: []Write{{"DELETE", "t", true}}, where: true},
+		{name: "delete in cte pg", dialect: PostgreSQL, sql: "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d",
+			typ: TypeDML, verb: "SELECT", tables: []string{"t"}, writes: []Write{{"DELETE", "t", false}}},
+		{name: "update tautology", dialect: PostgreSQL, sql: "UPDATE t SET a = 1 WHERE 1 = 1",
+			typ: TypeDML, verb: "UPDATE", tables: []string{"t"}, writes: []Write{{"UPDATE", "t", true}}, where: true, tautology: true},
+		{name: "or true", dialect: MySQL, sql: "SELECT a FROM t WHERE id = 5 OR TRUE",
+			typ: TypeRead, verb: "SELECT", tables: []string{"t"}, where: true, tautology: true},
+
+		// 权限和结构变更
+		{name: "grant", dialect: MySQL, sql: "GRANT ALL ON *.* TO 'u'@'%'", typ: TypeDCL, verb: "GRANT"},
+		{name: "grant pg", dialect: PostgreSQL, sql: "GRANT SELECT ON t TO u", typ: TypeDCL, verb: "GRANT", tables: []string{"t"}},
+		{name: "create user", dialect: PostgreSQL, sql: "CREATE USER u WITH PASSWORD 'p'", typ: TypeDCL, verb: "CREATE USER"},
+		{name: "drop table", dialect: MySQL, sql: "DROP TABLE IF EXISTS a, b",
+			typ: TypeDDL, verb: "DROP TABLE", tables: []string{"a", "b"}, writes: []Write{{"DROP TABLE", "a", true}, {"DROP TABLE", "b", true}}},
+		{name: "truncate pg", dialect: PostgreSQL, sql: "TRUNCATE public.t",
+			typ: TypeDDL, verb: "TRUNCATE", tables: []string{"public.t"}, writes: []Write{{"TRUNCATE", "public.t", true}}},
+		{name: "select into pg creates table", dialect: PostgreSQL, sql: "SELECT * INTO t2 FROM t",
+			typ: TypeDDL, verb: "SELECT", tables: []string{"t2", "t"}, writes: []Write{{"SELECT INTO", "t2", true}}},
+
+		// 只读语句中的副作用
+		{name: "set_config", dialect: PostgreSQL, sql: "SELECT set_config('role', 'x', false)",
+			typ: TypeRead, verb: "SELECT", functions: []string{"SET_CONFIG"}},
+		{name: "qualified nextval", dialect: PostgreSQL, sql: "SELECT pg_catalog.nextval('s')",
+			typ: TypeRead, verb: "SELECT", functions: []string{"NEXTVAL"}},
+		{name: "locking read", dialect: MySQL, sql: "SELECT a FROM t WHERE id = 1 FOR UPDATE",
+			typ: TypeRead, verb: "SELECT", tables: []string{"t"}, where: true, locking: true},
+		{name: "locking read pg", dialect: PostgreSQL, sql: "SELECT a FROM t WHERE id = 1 FOR SHARE",
+			typ: TypeRead, verb: "SELECT", tables: []string{"t"}, where: true, locking: true},
+		{name: "explain does not execute", dialect: PostgreSQL, sql: "EXPLAIN DELETE FROM t",
+			typ: TypeRead, verb: "EXPLAIN", tables: []string{"t"}},
+		{name: "explain analyze executes", dialect: MySQL, sql: "EXPLAIN ANALYZE DELETE FROM t",
+			typ: TypeDML, verb: "EXPLAIN", tables: []string{"t"}, writes: []Write{{"DELETE", "t", false}}},
+
+		// 其他语句
+		{name: "set global", dialect: MySQL, sql: "SET GLOBAL max_connections = 10", typ: TypeOther, verb: "SET GLOBAL"},
+		{name: "use switches schema", dialect: MySQL, sql: "USE other_db", typ: TypeOther, verb: "USE"},
+		{name: "copy from file", dialect: PostgreSQL, sql: "COPY t FROM '/tmp/a.csv'",
+			typ: TypeDML, verb: "COPY", tables: []string{"t"}, writes: []Write{{"COPY", "t", true}}},
+		{name: "begin", dialect: PostgreSQL, sql: "BEGIN", typ: TypeTCL, verb: "BEGIN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := Parse(tt.sql, tt.dialect)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(statements) != 1 {
+				t.Fatalf("Parse() returned %d statements, want 1", len(statements))
+			}
+			stmt := statements[0]
+			if stmt.Type != tt.typ || stmt.Verb != tt.verb {
+				t.Errorf("type/verb = %s/%s, want %s/%s", stmt.Type, stmt.Verb, tt.typ, tt.verb)
+			}
+			if !equalStrings(stmt.Tables, tt.tables) {
+				t.Errorf("Tables = %v, want %v", stmt.Tables, tt.tables)
+			}
+			if len(stmt.Writes) != len(tt.writes) || (len(tt.writes) > 0 && !reflect.DeepEqual(stmt.Writes, tt.writes)) {
+				t.Errorf("Writes = %v, want %v", stmt.Writes, tt.writes)
+			}
+			for _, fn := range tt.functions {
+				if !contains(stmt.Functions, fn) {
+					t.Errorf("Functions = %v, want %s", stmt.Functions, fn)
+				}
+			}
+			if stmt.HasWhere != tt.where {
+				t.Errorf("HasWhere = %v, want %v", stmt.HasWhere, tt.where)
+			}
+			if stmt.Locking != tt.locking {
+				t.Errorf("Locking = %v, want %v", stmt.Locking, tt.locking)
+			}
+			if stmt.Tautology != tt.tautology {
+				t.Errorf("Tautology = %v, want %v", stmt.Tautology, tt.tautology)
+			}
+		})
+	}
+}
+
+func TestParseMultipleStatements(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		sql     string
+		texts   []string
+	}{
+		{MySQL, "SELECT ';' FROM t; DELETE FROM t", []string{"SELECT ';' FROM t", "DELETE FROM t"}},
+		{PostgreSQL, "SELECT ';' FROM t; DELETE FROM t", []string{"SELECT ';' FROM t", "DELETE FROM t"}},
+	}
+	for _, tt := range tests {
+		statements, err := Parse(tt.sql, tt.dialect)
+		if err != nil {
+			t.Fatalf("%s: Parse() error = %v", tt.dialect, err)
+		}
+		var texts []string
+		for _, stmt := range statements {
+			texts = append(texts, stmt.Text)
+		}
+		if !reflect.DeepEqual(texts, tt.texts) {
+			t.Errorf("%s: texts = %q, want %q", tt.dialect, texts, tt.texts)
+		}
+	}
+}
+
+// 语法错误和不允许出现修改的位置返回错误，而不是按查询处理
+func TestParseRejectsInvalidSQL(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		sql     string
+	}{
+		{PostgreSQL, "SELECT * FROM (UPDATE t SET a = 1 RETURNING *) x"},
+		{MySQL, "SELECT * FROM t WHERE"},
+		{MySQL, "SELEC 1"},
+		{PostgreSQL, "SELECT 'unterminated"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.sql, tt.dialect); err == nil {
+			t.Errorf("%s: Parse(%q) error = nil, want error", tt.dialect, tt.sql)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -46,6 +46,8 @@ func InitRouter(r *gin.Engine) {
 			{
 				dashboard.GET("/overview", dashboardApi.GetOverview)
 				dashboard.GET("/task-chart", dashboardApi.GetTaskChart)
+				dashboard.GET("/task-breakdown", dashboardApi.GetTaskBreakdown)
+				dashboard.POST("/task-statistics/rebuild", dashboardApi.RebuildTaskStatistics)
 				dashboard.GET("/sms-chart", dashboardApi.GetSmsChart)
 			}
 
@@ -59,10 +61,78 @@ func InitRouter(r *gin.Engine) {
 				taskAPI.DELETE("/:id", v1.DeleteTask)
 				taskAPI.DELETE("/batch", v1.BatchDeleteTasks)
 				taskAPI.GET("/:id/logs", v1.GetTaskLogs)
+				taskAPI.POST("/:id/logs/purge", v1.PurgeTaskLogs)
+				taskAPI.GET("/:id/log-archives", v1.GetTaskLogArchives)
+				taskAPI.GET("/:id/log-archives/:archiveId/download", v1.DownloadTaskLogArchive)
 				taskAPI.POST("/:id/run", v1.RunTask)
+				taskAPI.GET("/:id/runs", v1.GetTaskRuns)
+				taskAPI.POST("/:id/runs/:runId/cancel", v1.CancelTaskRun)
+				taskAPI.GET("/:id/runs/:runId/output", v1.StreamTaskRunOutput)
+				taskAPI.GET("/export", v1.ExportTasks)
+				taskAPI.GET("/:id/export", v1.ExportTask)
+				taskAPI.POST("/import", v1.ImportTasks)
+				taskAPI.GET("/:id/revisions", v1.GetTaskRevisions)
+				taskAPI.GET("/:id/revisions/diff", v1.DiffTaskRevisions)
+				taskAPI.GET("/:id/revisions/:revision", v1.GetTaskRevision)
+				taskAPI.POST("/:id/revisions/:revision/rollback", v1.RollbackTask)
 				taskAPI.GET("/cron-patterns", v1.GetCommonCronPatterns)
 				taskAPI.PATCH("/:id/status", v1.UpdateTaskStatus)
 				taskAPI.GET("/next-run-times", v1.GetNextRunTimes) // 新增：获取下次执行时间
+				taskAPI.GET("/graph", v1.GetTaskGraph)
+			}
+
+			// 业务日历
+			calendarAPI := v1Group.Group("/calendar")
+			{
+				calendarAPI.GET("", v1.GetCalendars)
+				calendarAPI.GET("/:id", v1.GetCalendar)
+				calendarAPI.POST("", v1.CreateCalendar)
+				calendarAPI.PUT("/:id", v1.UpdateCalendar)
+				calendarAPI.DELETE("/:id", v1.DeleteCalendar)
+			}
+
+			// 数据库连接管理
+			databaseAPI := v1Group.Group("/database")
+			{
+				databaseAPI.GET("", v1.GetDatabases)
+				databaseAPI.POST("", v1.CreateDatabase)
+				databaseAPI.POST("/test", v1.TestConnection)
+				databaseAPI.POST("/query", v1.ExecuteQuery)
+				databaseAPI.POST("/query/:queryId/cancel", v1.CancelQuery)
+				databaseAPI.POST("/analyze", v1.AnalyzeSQL)
+				databaseAPI.GET("/tables", v1.GetTables)
+				databaseAPI.GET("/table-schema", v1.GetTableSchema)
+				databaseAPI.GET("/:id", v1.GetDatabase)
+				databaseAPI.PUT("/:id", v1.UpdateDatabase)
+				databaseAPI.DELETE("/:id", v1.DeleteDatabase)
+				databaseAPI.POST("/:id/test", v1.TestDatabaseConnection)
+			}
+
+			// SQL变更工单
+			sqlTicketAPI := v1Group.Group("/sql-ticket")
+			{
+				sqlTicketAPI.GET("", v1.GetSQLTickets)
+				sqlTicketAPI.POST("", v1.CreateSQLTicket)
+				sqlTicketAPI.GET("/:id", v1.GetSQLTicket)
+				sqlTicketAPI.POST("/:id/approve", v1.ApproveSQLTicket)
+				sqlTicketAPI.POST("/:id/reject", v1.RejectSQLTicket)
+				sqlTicketAPI.POST("/:id/cancel", v1.CancelSQLTicket)
+				sqlTicketAPI.POST("/:id/execute", v1.ExecuteSQLTicket)
+			}
+
+			// 告警相关路由
+			alertAPI := v1Group.Group("/alert")
+			{
+				alertAPI.GET("/rules", v1.GetAlertRules)
+				alertAPI.POST("/rules", v1.CreateAlertRule)
+				alertAPI.PUT("/rules/:id", v1.UpdateAlertRule)
+				alertAPI.DELETE("/rules/:id", v1.DeleteAlertRule)
+				alertAPI.GET("/channels", v1.GetAlertChannels)
+				alertAPI.POST("/channels", v1.CreateAlertChannel)
+				alertAPI.PUT("/channels/:id", v1.UpdateAlertChannel)
+				alertAPI.DELETE("/channels/:id", v1.DeleteAlertChannel)
+				alertAPI.POST("/channels/:id/test", v1.TestAlertChannel)
+				alertAPI.GET("/events", v1.GetAlertEvents)
 			}
 
 			// 用户相关路由
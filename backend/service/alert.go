@@ -0,0 +1,290 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+)
+
+type AlertService struct{}
+
+// ListRules 获取告警规则，taskID不为0时返回对该任务生效的规则（含全局规则）
+func (s *AlertService) ListRules(taskID uint) ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	query := db.Db.Order("id")
+	if taskID != 0 {
+		query = query.Where("task_id IN ?", []uint{0, taskID})
+	}
+	if err := query.Find(&rules).Error; err != nil {
+		log.Error(fmt.Sprintf("获取告警规则失败: %v", err))
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRule 获取告警规则
+func (s *AlertService) GetRule(id uint) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	if err := db.Db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ValidateRule 校验告警规则
+func (s *AlertService) ValidateRule(rule *model.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("规则名称不能为空")
+	}
+	if _, ok := model.AlertRuleTypeMap[rule.Type]; !ok {
+		return fmt.Errorf("无效的规则类型: %s", rule.Type)
+	}
+	switch rule.Type {
+	case model.AlertRuleFailure:
+		rule.Threshold = 0
+	case model.AlertRuleConsecutiveFailures:
+		if rule.Threshold < 2 {
+			return fmt.Errorf("连续失败次数不能小于2")
+		}
+	default:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("阈值必须大于0")
+		}
+	}
+	if rule.Interval < 0 {
+		return fmt.Errorf("通知间隔不能为负数")
+	}
+	if rule.TaskID != 0 {
+		if err := db.Db.Select("id").First(&model.Task{}, rule.TaskID).Error; err != nil {
+			return fmt.Errorf("任务不存在, ID: %d", rule.TaskID)
+		}
+	}
+	if len(rule.ChannelIDs) == 0 {
+		return fmt.Errorf("告警通道不能为空")
+	}
+	var count int64
+	if err := db.Db.Model(&model.AlertChannel{}).Where("id IN ?", []uint(rule.ChannelIDs)).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(rule.ChannelIDs) {
+		return fmt.Errorf("告警通道不存在")
+	}
+	return nil
+}
+
+// CreateRule 创建告警规则
+func (s *AlertService) CreateRule(rule *model.AlertRule) error {
+	if err := s.ValidateRule(rule); err != nil {
+		return err
+	}
+	if err := db.Db.Create(rule).Error; err != nil {
+		log.Error(fmt.Sprintf("创建告警规则失败: %v", err))
+		return err
+	}
+	return nil
+}
+
+// UpdateRule 更新告警规则
+func (s *AlertService) UpdateRule(rule *model.AlertRule) error {
+	if err := s.ValidateRule(rule); err != nil {
+		return err
+	}
+	if err := db.Db.Save(rule).Error; err != nil {
+		log.Error(fmt.Sprintf("更新告警规则失败, ID: %d, 错误: %v", rule.ID, err))
+		return err
+	}
+	return nil
+}
+
+// DeleteRule 删除告警规则，该规则告警中的事件一并标记为已恢复，不发送恢复通知
+func (s *AlertService) DeleteRule(id uint) error {
+	if err := db.Db.Delete(&model.AlertRule{}, id).Error; err != nil {
+		log.Error(fmt.Sprintf("删除告警规则失败, ID: %d, 错误: %v", id, err))
+		return err
+	}
+	return db.Db.Model(&model.AlertEvent{}).Where("rule_id = ? AND status = ?", id, model.AlertEventFiring).
+		Updates(map[string]interface{}{"status": model.AlertEventResolved, "firing": nil, "resolved_at": time.Now()}).Error
+}
+
+// ListChannels 获取告警通道
+func (s *AlertService) ListChannels() ([]*model.AlertChannel, error) {
+	var channels []*model.AlertChannel
+	if err := db.Db.Order("id").Find(&channels).Error; err != nil {
+		log.Error(fmt.Sprintf("获取告警通道失败: %v", err))
+		return nil, err
+	}
+	return channels, nil
+}
+
+// GetChannel 获取告警通道
+func (s *AlertService) GetChannel(id uint) (*model.AlertChannel, error) {
+	var channel model.AlertChannel
+	if err := db.Db.First(&channel, id).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// ValidateChannel 使用通道类型对应的实现校验通道配置
+func (s *AlertService) ValidateChannel(channel *model.AlertChannel) error {
+	if channel.Name == "" {
+		return fmt.Errorf("通道名称不能为空")
+	}
+	notifier, ok := GetNotifier(channel.Type)
+	if !ok {
+		return fmt.Errorf("无效的通道类型: %s", channel.Type)
+	}
+	return notifier.Validate(channel.Config)
+}
+
+// sealChannel 加密通道配置中的敏感信息，old为已存储的配置
+func (s *AlertService) sealChannel(channel *model.AlertChannel, old string) error {
+	notifier, ok := GetNotifier(channel.Type)
+	if !ok {
+		return fmt.Errorf("无效的通道类型: %s", channel.Type)
+	}
+	sealer, ok := notifier.(SecretConfigNotifier)
+	if !ok {
+		return nil
+	}
+	config, err := sealer.SealConfig(channel.Config, old)
+	if err != nil {
+		return err
+	}
+	channel.Config = config
+	return nil
+}
+
+// MaskChannel 将通道配置中的敏感信息替换为掩码，用于接口响应
+func (s *AlertService) MaskChannel(channel *model.AlertChannel) {
+	notifier, ok := GetNotifier(channel.Type)
+	if !ok {
+		return
+	}
+	if sealer, ok := notifier.(SecretConfigNotifier); ok {
+		channel.Config = sealer.MaskConfig(channel.Config)
+	}
+}
+
+// CreateChannel 创建告警通道
+func (s *AlertService) CreateChannel(channel *model.AlertChannel) error {
+	if err := s.ValidateChannel(channel); err != nil {
+		return err
+	}
+	if err := s.sealChannel(channel, ""); err != nil {
+		return err
+	}
+	if err := db.Db.Create(channel).Error; err != nil {
+		log.Error(fmt.Sprintf("创建告警通道失败: %v", err))
+		return err
+	}
+	return nil
+}
+
+// UpdateChannel 更新告警通道，配置中敏感信息为掩码时保留原值
+func (s *AlertService) UpdateChannel(channel *model.AlertChannel) error {
+	if err := s.ValidateChannel(channel); err != nil {
+		return err
+	}
+	var current model.AlertChannel
+	if err := db.Db.Select("id", "config").First(&current, channel.ID).Error; err != nil {
+		return err
+	}
+	if err := s.sealChannel(channel, current.Config); err != nil {
+		return err
+	}
+	if err := db.Db.Save(channel).Error; err != nil {
+		log.Error(fmt.Sprintf("更新告警通道失败, ID: %d, 错误: %v", channel.ID, err))
+		return err
+	}
+	return nil
+}
+
+// RotateChannelSecrets 用当前主密钥重新加密所有告警通道配置中的敏感信息，未加密的历史配置同时被加密
+func (s *AlertService) RotateChannelSecrets() (*model.SecretRotationResult, error) {
+	var list []model.AlertChannel
+	if err := db.Db.Select("id", "type", "config").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	result := &model.SecretRotationResult{Total: len(list)}
+	for i := range list {
+		channel := &list[i]
+		old := channel.Config
+		if err := s.sealChannel(channel, old); err != nil {
+			log.Error(fmt.Sprintf("重新加密告警通道配置失败, ID: %d, 错误: %v", channel.ID, err))
+			result.Failed++
+			continue
+		}
+		if channel.Config == old {
+			continue
+		}
+		// 只在配置未被并发修改时更新
+		res := db.Db.Model(&model.AlertChannel{}).Where("id = ? AND config = ?", channel.ID, old).
+			UpdateColumn("config", channel.Config)
+		if res.Error != nil {
+			return result, res.Error
+		}
+		if res.RowsAffected > 0 {
+			result.Rotated++
+		}
+	}
+	return result, nil
+}
+
+// DeleteChannel 删除告警通道，仍被告警规则引用时不允许删除
+func (s *AlertService) DeleteChannel(id uint) error {
+	var rules []*model.AlertRule
+	if err := db.Db.Select("id", "name", "channel_ids").Find(&rules).Error; err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.ChannelIDs.Contains(id) {
+			return fmt.Errorf("通道被告警规则「%s」引用，无法删除", rule.Name)
+		}
+	}
+	if err := db.Db.Delete(&model.AlertChannel{}, id).Error; err != nil {
+		log.Error(fmt.Sprintf("删除告警通道失败, ID: %d, 错误: %v", id, err))
+		return err
+	}
+	return nil
+}
+
+// TestChannel 通过通道发送一条测试消息
+func (s *AlertService) TestChannel(id uint) error {
+	channel, err := s.GetChannel(id)
+	if err != nil {
+		return err
+	}
+	return sendAlert(channel, &model.AlertMessage{
+		Status:  model.AlertEventFiring,
+		Title:   "[测试] 告警通道测试",
+		Content: fmt.Sprintf("这是一条来自告警通道「%s」的测试消息", channel.Name),
+		Time:    time.Now(),
+	})
+}
+
+// ListEvents 分页获取告警事件，按触发时间倒序
+func (s *AlertService) ListEvents(page, pageSize int, taskID uint, status string) ([]*model.AlertEvent, int64, error) {
+	var events []*model.AlertEvent
+	var total int64
+	query := db.Db.Model(&model.AlertEvent{})
+	if taskID != 0 {
+		query = query.Where("task_id = ?", taskID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		log.Error(fmt.Sprintf("获取告警事件总数失败: %v", err))
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id desc").Find(&events).Error; err != nil {
+		log.Error(fmt.Sprintf("获取告警事件失败: %v", err))
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+)
+
+// emailConfig 邮件通道配置，SMTP服务器在配置文件的 alert.smtp 中设置
+type emailConfig struct {
+	To []string `json:"to"`
+}
+
+// emailNotifier 通过SMTP发送告警邮件
+type emailNotifier struct{}
+
+func init() {
+	RegisterNotifier(model.AlertChannelEmail, &emailNotifier{})
+}
+
+func (n *emailNotifier) parse(cfgText string) (*emailConfig, error) {
+	var cfg emailConfig
+	if err := json.Unmarshal([]byte(cfgText), &cfg); err != nil {
+		return nil, fmt.Errorf("邮件配置格式错误: %v", err)
+	}
+	if len(cfg.To) == 0 {
+		return nil, fmt.Errorf("收件人不能为空")
+	}
+	for _, to := range cfg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("无效的收件人: %s", to)
+		}
+	}
+	return &cfg, nil
+}
+
+// Validate 校验邮件通道配置
+func (n *emailNotifier) Validate(cfgText string) error {
+	_, err := n.parse(cfgText)
+	return err
+}
+
+// Notify 发送告警邮件
+func (n *emailNotifier) Notify(ctx context.Context, cfgText string, msg *model.AlertMessage) error {
+	cfg, err := n.parse(cfgText)
+	if err != nil {
+		return err
+	}
+	server := config.Config.Alert.Smtp
+	if server.Host == "" || server.From == "" {
+		return fmt.Errorf("未配置SMTP服务器")
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", server.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Title))
+	fmt.Fprintf(&body, "Date: %s\r\n", msg.Time.Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body.WriteString(msg.Content)
+
+	var auth smtp.Auth
+	if server.Username != "" {
+		auth = smtp.PlainAuth("", server.Username, server.Password, server.Host)
+	}
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+
+	done := make(chan error, 1)
+	go func() {
+		if !server.SSL {
+			// 服务器支持时自动使用STARTTLS
+			done <- smtp.SendMail(addr, auth, server.From, cfg.To, body.Bytes())
+			return
+		}
+		done <- sendMailTLS(addr, server.Host, auth, server.From, cfg.To, body.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("发送邮件超时")
+	}
+}
+
+// sendMailTLS 通过SSL连接发送邮件
+func sendMailTLS(addr, host string, auth smtp.Auth, from string, to []string, body []byte) error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: alertNotifyTimeout}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
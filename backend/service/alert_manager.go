@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAlertCheckInterval = 30 * time.Second
+	maxAlertErrorSize         = 500 // 告警内容中最多包含的错误信息长度
+)
+
+// TaskAlerter 全局任务告警
+var TaskAlerter = newAlertManager()
+
+// alertManager 根据告警规则检查任务执行情况，触发告警和恢复通知。
+// 执行失败类规则在执行结束时检查，执行超时和未按时开始类规则定时检查
+type alertManager struct {
+	interval time.Duration
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newAlertManager() *alertManager {
+	m := &alertManager{
+		interval: defaultAlertCheckInterval,
+		stopCh:   make(chan struct{}),
+	}
+	if seconds := config.Config.Alert.CheckInterval; seconds > 0 {
+		m.interval = time.Duration(seconds) * time.Second
+	}
+	return m
+}
+
+// Start 启动定时检查
+func (m *alertManager) Start() {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+	log.Info(fmt.Sprintf("任务告警检查已启动, 间隔: %s", m.interval))
+}
+
+// Stop 停止定时检查
+func (m *alertManager) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
+
+// check 检查执行超时和未按时开始的任务
+func (m *alertManager) check() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("任务告警检查异常: %v", r))
+		}
+	}()
+	now := time.Now()
+	m.checkDurations(now)
+	m.checkStartDelays(now)
+}
+
+// checkDurations 检查本实例上执行时长超过阈值的执行
+func (m *alertManager) checkDurations(now time.Time) {
+	rules, err := m.loadRules(model.AlertRuleDuration, 0)
+	if err != nil || len(rules) == 0 {
+		return
+	}
+	for _, run := range taskRuns.all() {
+		startedAt, ok := run.StartedAt()
+		if !ok {
+			continue
+		}
+		elapsed := now.Sub(startedAt)
+		for _, rule := range rules {
+			if !ruleApplies(rule, run.Task.ID) || elapsed < time.Duration(rule.Threshold)*time.Second {
+				continue
+			}
+			m.fire(rule, run.Task, run.ID, fmt.Sprintf("批次 %s 已执行 %s，超过预期时长 %d 秒",
+				run.ID, elapsed.Truncate(time.Second), rule.Threshold))
+		}
+	}
+}
+
+// checkStartDelays 检查超过计划时间仍未开始执行的任务：
+// 主节点检查调度未触发的任务，各实例检查本实例执行池中排队过久的执行
+func (m *alertManager) checkStartDelays(now time.Time) {
+	rules, err := m.loadRules(model.AlertRuleStartDelay, 0)
+	if err != nil || len(rules) == 0 {
+		return
+	}
+
+	for _, run := range taskRuns.all() {
+		if _, started := run.StartedAt(); started {
+			continue
+		}
+		for _, rule := range rules {
+			delay := time.Duration(rule.Threshold) * time.Minute
+			if !ruleApplies(rule, run.Task.ID) || now.Sub(run.ScheduledTime) < delay {
+				continue
+			}
+			m.fire(rule, run.Task, run.ID, fmt.Sprintf("批次 %s 计划于 %s 执行，排队已超过 %d 分钟仍未开始",
+				run.ID, run.ScheduledTime.Format("2006-01-02 15:04:05"), rule.Threshold))
+		}
+	}
+
+	if !TaskScheduler.elector.isLeader() {
+		return
+	}
+	overdue := make(map[string]bool)
+	for _, rule := range rules {
+		query := db.Db.Where("status = ? AND cron_expr <> '' AND next_run_time < ?",
+			model.TaskStatusStarted, now.Add(-time.Duration(rule.Threshold)*time.Minute))
+		if rule.TaskID != 0 {
+			query = query.Where("id = ?", rule.TaskID)
+		}
+		var tasks []*model.Task
+		if err := query.Find(&tasks).Error; err != nil {
+			log.Error(fmt.Sprintf("加载未按时开始的任务失败: %v", err))
+			continue
+		}
+		for _, task := range tasks {
+			overdue[fmt.Sprintf("%d-%d", rule.ID, task.ID)] = true
+			m.fire(rule, task, "", fmt.Sprintf("任务计划于 %s 执行，已超过 %d 分钟仍未开始",
+				task.NextRunTime.Format("2006-01-02 15:04:05"), rule.Threshold))
+		}
+	}
+
+	// 调度恢复后（如停机期间错过的调度按策略跳过）不会有执行开始，由主节点恢复告警
+	var events []*model.AlertEvent
+	if err := db.Db.Where("status = ? AND run_id = ''", model.AlertEventFiring).Find(&events).Error; err != nil {
+		return
+	}
+	for _, event := range events {
+		rule := findRule(rules, event.RuleID)
+		if rule == nil || overdue[fmt.Sprintf("%d-%d", event.RuleID, event.TaskID)] {
+			continue
+		}
+		var task model.Task
+		if err := db.Db.First(&task, event.TaskID).Error; err != nil {
+			continue
+		}
+		m.resolveEvent(rule, &task, event, "任务调度已恢复正常")
+	}
+}
+
+// onRunStarted 执行开始时恢复该任务未按时开始的告警
+func (m *alertManager) onRunStarted(run *TaskRun) {
+	m.resolve(model.AlertRuleStartDelay, run.Task, "", fmt.Sprintf("批次 %s 已于 %s 开始执行",
+		run.ID, time.Now().Format("2006-01-02 15:04:05")))
+}
+
+// onRunFinished 执行结束时按最终结果检查失败类规则，成功时发送恢复通知；
+// 同时恢复该批次的执行超时告警
+func (m *alertManager) onRunFinished(run *TaskRun, result *ExecResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("任务告警检查异常, ID: %d, 错误: %v", run.Task.ID, r))
+		}
+	}()
+	task := run.Task
+	startedAt, _ := run.StartedAt()
+	duration := time.Since(startedAt).Truncate(time.Second)
+	m.resolve(model.AlertRuleDuration, task, run.ID, fmt.Sprintf("批次 %s 已结束，状态: %s", run.ID, result.Status))
+
+	switch result.Status {
+	case model.TaskExecStatusFailed:
+		message := fmt.Sprintf("批次 %s 执行失败，耗时 %s，共执行 %d 次", run.ID, duration, run.Attempt())
+		if result.Error != "" {
+			message += "\n错误信息: " + truncateText(result.Error, maxAlertErrorSize)
+		}
+		if rules, err := m.loadRules(model.AlertRuleFailure, task.ID); err == nil {
+			for _, rule := range rules {
+				m.fire(rule, task, run.ID, message)
+			}
+		}
+		if rules, err := m.loadRules(model.AlertRuleConsecutiveFailures, task.ID); err == nil {
+			for _, rule := range rules {
+				if n := consecutiveFailures(task.ID, rule.Threshold); n >= rule.Threshold {
+					m.fire(rule, task, run.ID, fmt.Sprintf("任务已连续失败 %d 次\n%s", n, message))
+				}
+			}
+		}
+	case model.TaskExecStatusSuccess:
+		message := fmt.Sprintf("批次 %s 执行成功，耗时 %s", run.ID, duration)
+		m.resolve(model.AlertRuleFailure, task, "", message)
+		m.resolve(model.AlertRuleConsecutiveFailures, task, "", message)
+	}
+}
+
+// consecutiveFailures 返回任务最近连续失败的次数，最多统计limit次；
+// 只统计执行批次的最终结果，取消和跳过的执行不打断也不计入连续失败
+func consecutiveFailures(taskID uint, limit int) int {
+	var logs []*model.TaskLog
+	if err := db.Db.Select("status").
+		Where("task_id = ? AND retried = ? AND status IN ?", taskID, false,
+			[]model.TaskExecStatus{model.TaskExecStatusSuccess, model.TaskExecStatusFailed}).
+		Order("id desc").Limit(limit).Find(&logs).Error; err != nil {
+		log.Error(fmt.Sprintf("统计连续失败次数失败, ID: %d, 错误: %v", taskID, err))
+		return 0
+	}
+	count := 0
+	for _, l := range logs {
+		if l.Status != model.TaskExecStatusFailed {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// loadRules 加载启用的某类告警规则，taskID不为0时只加载对该任务生效的规则
+func (m *alertManager) loadRules(ruleType string, taskID uint) ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	query := db.Db.Where("type = ? AND disabled = ?", ruleType, false)
+	if taskID != 0 {
+		query = query.Where("task_id IN ?", []uint{0, taskID})
+	}
+	if err := query.Find(&rules).Error; err != nil {
+		log.Error(fmt.Sprintf("加载告警规则失败: %v", err))
+		return nil, err
+	}
+	return rules, nil
+}
+
+func ruleApplies(rule *model.AlertRule, taskID uint) bool {
+	return rule.TaskID == 0 || rule.TaskID == taskID
+}
+
+func findRule(rules []*model.AlertRule, id uint) *model.AlertRule {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// fire 触发告警。规则和任务已有告警中的事件时只累计次数，
+// 超过规则的通知间隔后再次通知，间隔为0时不重复通知。
+// 多个实例同时触发时由唯一索引保证只创建一个事件，由条件更新保证同一次通知只发送一次
+func (m *alertManager) fire(rule *model.AlertRule, task *model.Task, runID, message string) {
+	now := time.Now()
+	event, created, err := m.recordFiring(rule, task, runID, message, now)
+	if err != nil {
+		log.Error(fmt.Sprintf("保存告警事件失败, 规则: %d, 任务: %d, 错误: %v", rule.ID, task.ID, err))
+		return
+	}
+	if !created && !m.claimNotification(rule, event, now) {
+		return
+	}
+
+	title := fmt.Sprintf("[告警] 任务「%s」%s", task.Name, model.AlertRuleTypeMap[rule.Type])
+	event.NotifyError = m.notify(rule, newAlertMessage(model.AlertEventFiring, title, message, rule, task, event))
+	if err := db.Db.Model(event).Update("notify_error", event.NotifyError).Error; err != nil {
+		log.Error(fmt.Sprintf("更新告警事件失败, ID: %d, 错误: %v", event.ID, err))
+	}
+}
+
+// recordFiring 创建告警中的事件，已存在时累计触发次数，返回事件和是否新建。
+// 新建的事件视为已认领首次通知
+func (m *alertManager) recordFiring(rule *model.AlertRule, task *model.Task, runID, message string, now time.Time) (*model.AlertEvent, bool, error) {
+	var lastErr error
+	// 创建时与其他实例冲突或累计时事件恰好被恢复，重新加载后再试一次
+	for attempt := 0; attempt < 2; attempt++ {
+		var event model.AlertEvent
+		err := db.Db.Where("rule_id = ? AND task_id = ? AND status = ?", rule.ID, task.ID, model.AlertEventFiring).
+			First(&event).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			firing := true
+			event = model.AlertEvent{
+				RuleID:         rule.ID,
+				TaskID:         task.ID,
+				Status:         model.AlertEventFiring,
+				Firing:         &firing,
+				RunID:          runID,
+				Message:        message,
+				FireCount:      1,
+				NotifyCount:    1,
+				FiredAt:        now,
+				LastFiredAt:    now,
+				LastNotifiedAt: &now,
+			}
+			if lastErr = db.Db.Create(&event).Error; lastErr == nil {
+				return &event, true, nil
+			}
+			continue
+		case err != nil:
+			return nil, false, err
+		}
+
+		result := db.Db.Model(&model.AlertEvent{}).Where("id = ? AND status = ?", event.ID, model.AlertEventFiring).
+			Updates(map[string]interface{}{
+				"fire_count":    gorm.Expr("fire_count + 1"),
+				"last_fired_at": now,
+				"run_id":        runID,
+				"message":       message,
+			})
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected > 0 {
+			event.FireCount++
+			event.LastFiredAt = now
+			event.RunID = runID
+			event.Message = message
+			return &event, false, nil
+		}
+		lastErr = fmt.Errorf("告警事件已恢复, ID: %d", event.ID)
+	}
+	return nil, false, lastErr
+}
+
+// claimNotification 距上次通知已超过规则的通知间隔时认领本次通知，多个实例同时触发时只有一个认领成功
+func (m *alertManager) claimNotification(rule *model.AlertRule, event *model.AlertEvent, now time.Time) bool {
+	if rule.Interval <= 0 {
+		return false
+	}
+	result := db.Db.Model(&model.AlertEvent{}).
+		Where("id = ? AND (last_notified_at IS NULL OR last_notified_at <= ?)", event.ID, now.Add(-time.Duration(rule.Interval)*time.Minute)).
+		Updates(map[string]interface{}{
+			"notify_count":     gorm.Expr("notify_count + 1"),
+			"last_notified_at": now,
+		})
+	if result.Error != nil {
+		log.Error(fmt.Sprintf("更新告警事件失败, ID: %d, 错误: %v", event.ID, result.Error))
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+	event.NotifyCount++
+	event.LastNotifiedAt = &now
+	return true
+}
+
+// resolve 恢复任务某类规则告警中的事件，runID不为空时只恢复该批次触发的事件
+func (m *alertManager) resolve(ruleType string, task *model.Task, runID, message string) {
+	rules, err := m.loadRules(ruleType, task.ID)
+	if err != nil || len(rules) == 0 {
+		return
+	}
+	ids := make([]uint, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+
+	var events []*model.AlertEvent
+	query := db.Db.Where("rule_id IN ? AND task_id = ? AND status = ?", ids, task.ID, model.AlertEventFiring)
+	if runID != "" {
+		query = query.Where("run_id = ?", runID)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		log.Error(fmt.Sprintf("加载告警事件失败, 任务: %d, 错误: %v", task.ID, err))
+		return
+	}
+	for _, event := range events {
+		m.resolveEvent(findRule(rules, event.RuleID), task, event, message)
+	}
+}
+
+// resolveEvent 将事件标记为已恢复，已发送过告警通知时发送恢复通知
+func (m *alertManager) resolveEvent(rule *model.AlertRule, task *model.Task, event *model.AlertEvent, message string) {
+	now := time.Now()
+	result := db.Db.Model(&model.AlertEvent{}).Where("id = ? AND status = ?", event.ID, model.AlertEventFiring).
+		Updates(map[string]interface{}{"status": model.AlertEventResolved, "firing": nil, "resolved_at": now})
+	if result.Error != nil {
+		log.Error(fmt.Sprintf("更新告警事件失败, ID: %d, 错误: %v", event.ID, result.Error))
+		return
+	}
+	// 已被其他实例恢复
+	if result.RowsAffected == 0 || event.NotifyCount == 0 {
+		return
+	}
+
+	event.Status = model.AlertEventResolved
+	event.ResolvedAt = &now
+	title := fmt.Sprintf("[恢复] 任务「%s」%s已恢复", task.Name, model.AlertRuleTypeMap[rule.Type])
+	content := fmt.Sprintf("%s\n告警开始于 %s，持续 %s，期间触发 %d 次", message,
+		event.FiredAt.Format("2006-01-02 15:04:05"), now.Sub(event.FiredAt).Truncate(time.Second), event.FireCount)
+	if errMsg := m.notify(rule, newAlertMessage(model.AlertEventResolved, title, content, rule, task, event)); errMsg != "" {
+		db.Db.Model(event).Update("notify_error", errMsg)
+	}
+}
+
+// notify 发送消息到规则的所有启用的通道，返回发送失败的原因
+func (m *alertManager) notify(rule *model.AlertRule, msg *model.AlertMessage) string {
+	if len(rule.ChannelIDs) == 0 {
+		return ""
+	}
+	var channels []*model.AlertChannel
+	if err := db.Db.Where("id IN ? AND disabled = ?", []uint(rule.ChannelIDs), false).Find(&channels).Error; err != nil {
+		log.Error(fmt.Sprintf("加载告警通道失败, 规则: %d, 错误: %v", rule.ID, err))
+		return err.Error()
+	}
+
+	var errs []string
+	for _, channel := range channels {
+		if err := sendAlert(channel, msg); err != nil {
+			log.Error(fmt.Sprintf("发送告警失败, 通道: %s, 规则: %d, 任务: %d, 错误: %v", channel.Name, rule.ID, msg.TaskID, err))
+			errs = append(errs, fmt.Sprintf("%s: %v", channel.Name, err))
+		}
+	}
+	log.Info(fmt.Sprintf("已发送告警通知: %s, 通道数: %d, 失败: %d", msg.Title, len(channels), len(errs)))
+	return strings.Join(errs, "\n")
+}
+
+// sendAlert 通过一个通道发送消息
+func sendAlert(channel *model.AlertChannel, msg *model.AlertMessage) error {
+	notifier, ok := GetNotifier(channel.Type)
+	if !ok {
+		return fmt.Errorf("不支持的告警通道类型: %s", channel.Type)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertNotifyTimeout)
+	defer cancel()
+	return notifier.Notify(ctx, channel.Config, msg)
+}
+
+func newAlertMessage(status, title, content string, rule *model.AlertRule, task *model.Task, event *model.AlertEvent) *model.AlertMessage {
+	return &model.AlertMessage{
+		Status:   status,
+		Title:    title,
+		Content:  content,
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		RuleType: rule.Type,
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		RunID:    event.RunID,
+		EventID:  event.ID,
+		Time:     time.Now(),
+	}
+}
+
+// truncateText 截断过长的文本
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
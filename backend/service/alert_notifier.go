@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/secret"
+)
+
+const (
+	alertNotifyTimeout   = 10 * time.Second
+	maxNotifyRespLogSize = 512 // 通知失败时最多记录的响应体长度
+)
+
+// AlertNotifier 告警通道的发送实现，每种通道类型对应一个实现
+type AlertNotifier interface {
+	// Validate 校验通道配置，在创建、更新通道时调用
+	Validate(config string) error
+	// Notify 发送告警消息
+	Notify(ctx context.Context, config string, msg *model.AlertMessage) error
+}
+
+// SecretConfigNotifier 配置中含有需要加密存储的敏感信息的通道实现，可选
+type SecretConfigNotifier interface {
+	// SealConfig 加密配置中的敏感信息后返回用于存储的配置，值为掩码时取old(已存储的配置)中的原值；
+	// 已加密的值在主密钥轮换后用当前主密钥重新加密
+	SealConfig(config, old string) (string, error)
+	// MaskConfig 将配置中的敏感信息替换为掩码，用于接口响应
+	MaskConfig(config string) string
+}
+
+var (
+	notifiersMu sync.RWMutex
+	notifiers   = make(map[string]AlertNotifier)
+)
+
+// RegisterNotifier 注册告警通道实现，通道类型需在 model.AlertChannelTypeMap 中声明
+func RegisterNotifier(channelType string, notifier AlertNotifier) {
+	if _, ok := model.AlertChannelTypeMap[channelType]; !ok {
+		panic(fmt.Sprintf("未声明的告警通道类型: %s", channelType))
+	}
+
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+	if _, ok := notifiers[channelType]; ok {
+		panic(fmt.Sprintf("告警通道类型 %s 重复注册", channelType))
+	}
+	notifiers[channelType] = notifier
+}
+
+// GetNotifier 获取告警通道类型对应的实现
+func GetNotifier(channelType string) (AlertNotifier, bool) {
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+	notifier, ok := notifiers[channelType]
+	return notifier, ok
+}
+
+// webhookConfig Webhook通道配置，请求头的值通常为令牌，加密存储，响应中以掩码代替
+type webhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// webhookNotifier 以JSON POST告警消息到指定地址
+type webhookNotifier struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterNotifier(model.AlertChannelWebhook, &webhookNotifier{client: &http.Client{}})
+}
+
+func (n *webhookNotifier) parse(config string) (*webhookConfig, error) {
+	var cfg webhookConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return nil, fmt.Errorf("Webhook配置格式错误: %v", err)
+	}
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return nil, fmt.Errorf("无效的Webhook地址: %s", cfg.URL)
+	}
+	return &cfg, nil
+}
+
+// Validate 校验Webhook配置
+func (n *webhookNotifier) Validate(config string) error {
+	_, err := n.parse(config)
+	return err
+}
+
+// Notify 发送告警消息
+func (n *webhookNotifier) Notify(ctx context.Context, config string, msg *model.AlertMessage) error {
+	cfg, err := n.parse(config)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range cfg.Headers {
+		value, err := secret.Decrypt(v)
+		if err != nil {
+			return fmt.Errorf("解密请求头 %s 失败: %v", k, err)
+		}
+		req.Header.Set(k, value)
+	}
+	return postNotification(n.client, req)
+}
+
+// SealConfig 加密请求头的值，值为掩码时保留已存储的值
+func (n *webhookNotifier) SealConfig(config, old string) (string, error) {
+	cfg, err := n.parse(config)
+	if err != nil || len(cfg.Headers) == 0 {
+		return config, err
+	}
+	var stored webhookConfig
+	if old != "" {
+		json.Unmarshal([]byte(old), &stored)
+	}
+	ring, err := secret.Default()
+	if err != nil {
+		return "", err
+	}
+	for k, v := range cfg.Headers {
+		if v == model.PasswordMask {
+			if v = stored.Headers[k]; v == "" {
+				return "", fmt.Errorf("请求头 %s 没有已保存的值，请重新填写", k)
+			}
+		}
+		if !ring.NeedsRotation(v) {
+			cfg.Headers[k] = v
+			continue
+		}
+		plaintext, err := ring.Decrypt(v)
+		if err != nil {
+			return "", fmt.Errorf("解密请求头 %s 失败: %v", k, err)
+		}
+		if cfg.Headers[k], err = ring.Encrypt(plaintext); err != nil {
+			return "", fmt.Errorf("加密请求头 %s 失败: %v", k, err)
+		}
+	}
+	data, err := json.Marshal(cfg)
+	return string(data), err
+}
+
+// MaskConfig 请求头的值替换为掩码
+func (n *webhookNotifier) MaskConfig(config string) string {
+	var cfg webhookConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil || len(cfg.Headers) == 0 {
+		return config
+	}
+	for k := range cfg.Headers {
+		cfg.Headers[k] = model.PasswordMask
+	}
+	data, _ := json.Marshal(cfg)
+	return string(data)
+}
+
+// postNotification 发送通知请求，非2xx响应视为失败
+func postNotification(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxNotifyRespLogSize))
+		return fmt.Errorf("响应状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+)
+
+// phonePattern 手机号格式，允许带国际区号
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+
+// smsConfig 短信通道配置，短信网关在配置文件的 alert.sms 中设置
+type smsConfig struct {
+	Phones []string `json:"phones"`
+}
+
+// smsNotifier 通过短信网关发送告警短信
+type smsNotifier struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterNotifier(model.AlertChannelSms, &smsNotifier{client: &http.Client{}})
+}
+
+func (n *smsNotifier) parse(cfgText string) (*smsConfig, error) {
+	var cfg smsConfig
+	if err := json.Unmarshal([]byte(cfgText), &cfg); err != nil {
+		return nil, fmt.Errorf("短信配置格式错误: %v", err)
+	}
+	if len(cfg.Phones) == 0 {
+		return nil, fmt.Errorf("接收手机号不能为空")
+	}
+	for _, phone := range cfg.Phones {
+		if !phonePattern.MatchString(phone) {
+			return nil, fmt.Errorf("无效的手机号: %s", phone)
+		}
+	}
+	return &cfg, nil
+}
+
+// Validate 校验短信通道配置
+func (n *smsNotifier) Validate(cfgText string) error {
+	_, err := n.parse(cfgText)
+	return err
+}
+
+// Notify 发送告警短信，短信内容只包含标题
+func (n *smsNotifier) Notify(ctx context.Context, cfgText string, msg *model.AlertMessage) error {
+	cfg, err := n.parse(cfgText)
+	if err != nil {
+		return err
+	}
+	gateway := config.Config.Alert.Sms
+	if gateway.URL == "" {
+		return fmt.Errorf("未配置短信网关")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"phones":  cfg.Phones,
+		"content": msg.Title,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gateway.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if gateway.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+gateway.Token)
+	}
+	return postNotification(n.client, req)
+}
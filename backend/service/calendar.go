@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/cronutil"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+type CalendarService struct{}
+
+// List 获取所有日历及其规则
+func (s *CalendarService) List() ([]*model.Calendar, error) {
+	var calendars []*model.Calendar
+	if err := db.Db.Preload("Rules").Order("id").Find(&calendars).Error; err != nil {
+		log.Error(fmt.Sprintf("获取日历列表失败: %v", err))
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// Get 获取日历及其规则
+func (s *CalendarService) Get(id uint) (*model.Calendar, error) {
+	var calendar model.Calendar
+	if err := db.Db.Preload("Rules").First(&calendar, id).Error; err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// Validate 校验日历名称和规则
+func (s *CalendarService) Validate(calendar *model.Calendar) error {
+	if calendar.Name == "" {
+		return fmt.Errorf("日历名称不能为空")
+	}
+	for i := range calendar.Rules {
+		if err := calendar.Rules[i].Validate(); err != nil {
+			return fmt.Errorf("第%d条规则无效: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Create 创建日历
+func (s *CalendarService) Create(calendar *model.Calendar) error {
+	if err := s.Validate(calendar); err != nil {
+		return err
+	}
+	if err := db.Db.Create(calendar).Error; err != nil {
+		log.Error(fmt.Sprintf("创建日历失败: %v", err))
+		return err
+	}
+	return nil
+}
+
+// Update 更新日历，规则整体替换，并重新注册引用该日历的任务
+func (s *CalendarService) Update(calendar *model.Calendar) error {
+	if err := s.Validate(calendar); err != nil {
+		return err
+	}
+
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Calendar{ID: calendar.ID}).Updates(map[string]interface{}{
+			"name":        calendar.Name,
+			"description": calendar.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("calendar_id = ?", calendar.ID).Delete(&model.CalendarRule{}).Error; err != nil {
+			return err
+		}
+		for i := range calendar.Rules {
+			calendar.Rules[i].ID = 0
+			calendar.Rules[i].CalendarID = calendar.ID
+		}
+		if len(calendar.Rules) > 0 {
+			return tx.Create(&calendar.Rules).Error
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("更新日历失败, ID: %d, 错误: %v", calendar.ID, err))
+		return err
+	}
+
+	s.rescheduleTasks(calendar.ID)
+	return nil
+}
+
+// Delete 删除日历，仍被任务引用时不允许删除
+func (s *CalendarService) Delete(id uint) error {
+	var count int64
+	if err := db.Db.Model(&model.Task{}).Where("calendar_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("日历被 %d 个任务引用，无法删除", count)
+	}
+
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", id).Delete(&model.CalendarRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Calendar{}, id).Error
+	})
+}
+
+// rescheduleTasks 日历变更后重新注册引用该日历的已启动任务
+func (s *CalendarService) rescheduleTasks(calendarID uint) {
+	var ids []uint
+	if err := db.Db.Model(&model.Task{}).Where("calendar_id = ? AND status = ?", calendarID, model.TaskStatusStarted).
+		Pluck("id", &ids).Error; err != nil {
+		log.Error(fmt.Sprintf("加载引用日历的任务失败, 日历ID: %d, 错误: %v", calendarID, err))
+		return
+	}
+	for _, id := range ids {
+		if err := TaskScheduler.Reschedule(id); err != nil {
+			log.Error(fmt.Sprintf("更新任务调度失败, ID: %d, 错误: %v", id, err))
+		}
+	}
+}
+
+// buildSchedule 根据cron表达式、时区和日历构造调度，日历排除的时间不会触发
+func buildSchedule(cronExpr, timezone string, calendarID uint) (cron.Schedule, error) {
+	loc, err := cronutil.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := cronutil.ParseInLocation(cronExpr, loc)
+	if err != nil {
+		return nil, err
+	}
+	if calendarID == 0 {
+		return schedule, nil
+	}
+
+	var calendar model.Calendar
+	if err := db.Db.Preload("Rules").First(&calendar, calendarID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("日历不存在, ID: %d", calendarID)
+		}
+		return nil, err
+	}
+	return cronutil.Exclude(schedule, func(t time.Time) time.Time {
+		return calendar.ExcludedUntil(t.In(loc))
+	}), nil
+}
+
+// taskSchedule 构造任务的调度
+func taskSchedule(task *model.Task) (cron.Schedule, error) {
+	return buildSchedule(task.CronExpr, task.Timezone, task.CalendarID)
+}
+
+// taskNextRunTime 计算任务在from之后的下次执行时间，没有cron表达式或不会再执行时返回nil
+func taskNextRunTime(task *model.Task, from time.Time) (*time.Time, error) {
+	if task.CronExpr == "" {
+		return nil, nil
+	}
+	schedule, err := taskSchedule(task)
+	if err != nil {
+		return nil, err
+	}
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return nil, nil
+	}
+	return &next, nil
+}
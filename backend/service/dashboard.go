@@ -84,8 +84,12 @@ func (s *DashboardService) GetOverview() (*model.DashboardOverview, int) {
 	return overview, 0
 }
 
-// GetTaskChart 获取任务图表数据
-func (s *DashboardService) GetTaskChart(period string) ([]model.ChartData, int) {
+// GetTaskChart 获取任务图表数据，taskID或taskType不为0时只统计对应任务或类型
+func (s *DashboardService) GetTaskChart(period string, taskID uint, taskType int) ([]model.ChartData, int) {
+	if taskID != 0 || taskType != 0 {
+		return s.getTaskDetailChart(period, taskID, taskType)
+	}
+
 	days := 7
 	if period == "month" {
 		days = 30
@@ -112,15 +116,92 @@ func (s *DashboardService) GetTaskChart(period string) ([]model.ChartData, int)
 	chartData := make([]model.ChartData, 0)
 	for _, stat := range statistics {
 		chartData = append(chartData, model.ChartData{
-			Date:    stat.Date.Format("2006-01-02"),
-			Success: stat.SuccessCount,
-			Fail:    stat.FailCount,
+			Date:        stat.Date.Format("2006-01-02"),
+			Success:     stat.SuccessCount,
+			Fail:        stat.FailCount,
+			Total:       stat.TotalCount,
+			SuccessRate: stat.SuccessRate,
 		})
 	}
 
 	return chartData, 0
 }
 
+// getTaskDetailChart 按任务或类型汇总每日统计
+func (s *DashboardService) getTaskDetailChart(period string, taskID uint, taskType int) ([]model.ChartData, int) {
+	startDate, endDate := statsPeriod(period)
+
+	var rows []struct {
+		Date         time.Time
+		TotalCount   int
+		SuccessCount int
+		FailCount    int
+	}
+	query := db.Db.Model(&model.TaskDailyStatistics{}).
+		Select("date, SUM(total_count) AS total_count, SUM(success_count) AS success_count, SUM(fail_count) AS fail_count").
+		Where("date >= ? AND date <= ?", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if taskID != 0 {
+		query = query.Where("task_id = ?", taskID)
+	}
+	if taskType != 0 {
+		query = query.Where("task_type = ?", taskType)
+	}
+	if err := query.Group("date").Order("date").Scan(&rows).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务统计数据失败: %v", err))
+		return nil, 1
+	}
+
+	chartData := make([]model.ChartData, 0, len(rows))
+	for _, row := range rows {
+		chartData = append(chartData, model.ChartData{
+			Date:        row.Date.Format("2006-01-02"),
+			Success:     row.SuccessCount,
+			Fail:        row.FailCount,
+			Total:       row.TotalCount,
+			SuccessRate: successRate(row.SuccessCount, row.TotalCount),
+		})
+	}
+	return chartData, 0
+}
+
+// GetTaskBreakdown 获取周期内按任务(by=task)或按类型(by=type)汇总的执行统计，按执行次数倒序
+func (s *DashboardService) GetTaskBreakdown(period, by string) ([]*model.TaskStatisticsBreakdown, int) {
+	startDate, endDate := statsPeriod(period)
+
+	query := db.Db.Table("task_daily_statistics AS s").
+		Where("s.date >= ? AND s.date <= ?", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	sums := "SUM(s.total_count) AS total_count, SUM(s.success_count) AS success_count, " +
+		"SUM(s.fail_count) AS fail_count, SUM(s.cancel_count) AS cancel_count"
+	if by == "type" {
+		query = query.Select("s.task_type, " + sums).Group("s.task_type")
+	} else {
+		query = query.Select("s.task_id, MAX(t.name) AS task_name, MAX(s.task_type) AS task_type, "+sums).
+			Joins("LEFT JOIN tasks AS t ON t.id = s.task_id").
+			Group("s.task_id")
+	}
+
+	var breakdown []*model.TaskStatisticsBreakdown
+	if err := query.Order("total_count DESC").Scan(&breakdown).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务统计明细失败: %v", err))
+		return nil, 1
+	}
+	for _, item := range breakdown {
+		item.TypeName = model.TaskType(item.TaskType).String()
+		item.SuccessRate = successRate(item.SuccessCount, item.TotalCount)
+	}
+	return breakdown, 0
+}
+
+// statsPeriod 返回统计周期的起止日期，month为最近30天，其他为最近7天
+func statsPeriod(period string) (time.Time, time.Time) {
+	days := 7
+	if period == "month" {
+		days = 30
+	}
+	endDate := time.Now()
+	return endDate.AddDate(0, 0, -days+1), endDate
+}
+
 // GetSmsChart 获取短信图表数据
 func (s *DashboardService) GetSmsChart(period string) ([]model.ChartData, int) {
 	days := 7
@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
 	"time"
 	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/log"
+	"tools-admin/backend/pkg/secret"
+	"tools-admin/backend/pkg/sqlparser"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
@@ -17,6 +21,7 @@ import (
 type DatabaseService struct {
 	db            *gorm.DB
 	connPool      sync.Map // map[uint]*sql.DB
+	queries       sync.Map // map[string]*runningQuery，执行中的查询
 	sqlSecurity   *SQLSecurityService
 }
 
@@ -52,32 +57,80 @@ func (s *DatabaseService) List(req *model.DatabaseListReq) (*model.DatabaseListR
 		return nil, err
 	}
 
-	return &model.DatabaseListResp{
+	resp := &model.DatabaseListResp{
 		Total: total,
-		List:  list,
-	}, nil
+		List:  make([]*model.DatabaseResponse, 0, len(list)),
+	}
+	for i := range list {
+		resp.List = append(resp.List, list[i].ToResponse())
+	}
+	return resp, nil
+}
+
+// Get 获取数据库连接详情，密码以掩码返回
+func (s *DatabaseService) Get(id string) (*model.DatabaseResponse, error) {
+	var db model.Database
+	if err := s.db.First(&db, id).Error; err != nil {
+		return nil, err
+	}
+	return db.ToResponse(), nil
 }
 
-// Create 创建数据库连接
+// Create 创建数据库连接，密码加密存储
 func (s *DatabaseService) Create(req *model.DatabaseCreateReq) error {
+	password, err := secret.Encrypt(req.Password)
+	if err != nil {
+		return fmt.Errorf("加密密码失败: %v", err)
+	}
 	db := &model.Database{
 		Name:     req.Name,
 		Type:     req.Type,
 		Host:     req.Host,
 		Port:     req.Port,
 		Username: req.Username,
-		Password: req.Password,
+		Password: password,
 		Database: req.Database,
+		ApproverRoleIDs: req.ApproverRoleIDs,
+		QueryTimeout: req.QueryTimeout,
+		MaxRows:  req.MaxRows,
 	}
 
 	return s.db.Create(db).Error
 }
 
+// storedPassword 读取并解密已保存的数据库连接密码
+func (s *DatabaseService) storedPassword(dbConfig *model.Database) (string, error) {
+	password, err := secret.Decrypt(dbConfig.Password)
+	if err != nil {
+		return "", fmt.Errorf("解密数据库连接密码失败, ID: %d, 错误: %v", dbConfig.ID, err)
+	}
+	return password, nil
+}
+
 // TestConnection 测试数据库连接
 func (s *DatabaseService) TestConnection(req *model.DatabaseTestReq) error {
 	var db *sql.DB
 	var err error
 
+	// 编辑已有连接时页面上只有密码掩码，使用已保存的连接配置，
+	// 忽略请求中的地址和用户名，避免已保存的密码被发送到其他主机
+	if req.ID != 0 && req.Password == model.PasswordMask {
+		var dbConfig model.Database
+		if err := s.db.First(&dbConfig, req.ID).Error; err != nil {
+			return fmt.Errorf("database not found: %v", err)
+		}
+		password, err := s.storedPassword(&dbConfig)
+		if err != nil {
+			return err
+		}
+		req.Type = dbConfig.Type
+		req.Host = dbConfig.Host
+		req.Port = dbConfig.Port
+		req.Username = dbConfig.Username
+		req.Database = dbConfig.Database
+		req.Password = password
+	}
+
 	switch req.Type {
 	case "mysql":
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
@@ -118,16 +171,21 @@ func (s *DatabaseService) getConnection(dbID uint) (*sql.DB, error) {
 		return nil, fmt.Errorf("database not found: %v", err)
 	}
 
+	password, err := s.storedPassword(&dbConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建新连接
 	var db *sql.DB
 	switch dbConfig.Type {
 	case "mysql":
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			dbConfig.Username, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.Database)
+			dbConfig.Username, password, dbConfig.Host, dbConfig.Port, dbConfig.Database)
 		db, err = sql.Open("mysql", dsn)
 	case "postgresql":
 		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-			dbConfig.Host, dbConfig.Port, dbConfig.Username, dbConfig.Password, dbConfig.Database)
+			dbConfig.Host, dbConfig.Port, dbConfig.Username, password, dbConfig.Database)
 		db, err = sql.Open("postgres", dsn)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbConfig.Type)
@@ -141,27 +199,57 @@ func (s *DatabaseService) getConnection(dbID uint) (*sql.DB, error) {
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
 
-	// 存入连接池
-	s.connPool.Store(dbID, db)
+	// 存入连接池，并发创建时保留先存入的连接
+	if actual, loaded := s.connPool.LoadOrStore(dbID, db); loaded {
+		db.Close()
+		return actual.(*sql.DB), nil
+	}
 
 	return db, nil
 }
 
-// ExecuteQuery 执行SQL查询
-func (s *DatabaseService) ExecuteQuery(req *model.QueryExecuteReq, c *gin.Context) (*model.QueryExecuteResp, error) {
-	// 清理SQL语句
-	req.SQL = s.sqlSecurity.SanitizeSQL(req.SQL)
+// closeConnection 关闭并移除连接池中的连接，连接配置修改或删除后调用
+func (s *DatabaseService) closeConnection(dbID uint) {
+	if conn, ok := s.connPool.LoadAndDelete(dbID); ok {
+		conn.(*sql.DB).Close()
+	}
+}
 
-	// SQL安全检查
-	if err := s.sqlSecurity.ValidateSQL(req.SQL); err != nil {
-		return nil, err
+// prepareQuery 清理并检查控制台SQL，只允许执行单条查询语句
+func (s *DatabaseService) prepareQuery(req *model.QueryExecuteReq) (*model.Database, error) {
+	var dbConfig model.Database
+	if err := s.db.First(&dbConfig, req.DatabaseID).Error; err != nil {
+		return nil, fmt.Errorf("database not found: %v", err)
 	}
 
-	// SQL分析
-	analysis := s.sqlSecurity.AnalyzeSQL(req.SQL)
+	// 清理SQL语句
+	req.SQL = s.sqlSecurity.SanitizeSQL(req.SQL, dbConfig.Type)
+
+	// SQL分析，只允许执行单条查询语句
+	analysis := s.sqlSecurity.AnalyzeSQL(req.SQL, dbConfig.Type)
 	if analysis.Risk == model.RiskHigh {
 		return nil, fmt.Errorf("SQL存在高风险: %s, %s", analysis.Description, analysis.Suggestion)
 	}
+	if len(analysis.Statements) != 1 {
+		return nil, fmt.Errorf("每次只能执行一条SQL语句")
+	}
+	stmt := analysis.Statements[0]
+	if stmt.Type != string(sqlparser.TypeRead) || len(stmt.Writes) > 0 {
+		return nil, fmt.Errorf("只允许执行查询语句, 当前语句: %s", stmt.Verb)
+	}
+	// 加锁读会持有行锁直到事务结束，控制台不允许执行
+	if stmt.Locking {
+		return nil, fmt.Errorf("不允许执行加锁读(FOR UPDATE/FOR SHARE/LOCK IN SHARE MODE)")
+	}
+	return &dbConfig, nil
+}
+
+// ExecuteQuery 执行SQL查询。超过超时时间的查询被取消，结果超过行数上限时截断
+func (s *DatabaseService) ExecuteQuery(req *model.QueryExecuteReq, c *gin.Context) (*model.QueryExecuteResp, error) {
+	dbConfig, err := s.prepareQuery(req)
+	if err != nil {
+		return nil, err
+	}
 
 	// 获取数据库连接
 	db, err := s.getConnection(req.DatabaseID)
@@ -169,12 +257,27 @@ func (s *DatabaseService) ExecuteQuery(req *model.QueryExecuteReq, c *gin.Contex
 		return nil, err
 	}
 
+	queryID := req.QueryID
+	if queryID == "" {
+		queryID = newQueryID()
+	}
+	timeout, maxRows := queryLimits(dbConfig)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
 	// 记录开始时间
 	startTime := time.Now()
 
 	// 执行查询
-	rows, err := db.Query(req.SQL)
-	
+	query := &runningQuery{
+		id:        queryID,
+		userID:    c.GetUint("user_id"),
+		dbType:    dbConfig.Type,
+		cancel:    cancel,
+		startedAt: startTime,
+	}
+	resp, err := s.runQuery(ctx, db, query, req.SQL, maxRows)
+
 	// 计算执行时长
 	duration := time.Since(startTime).Milliseconds()
 
@@ -186,79 +289,40 @@ func (s *DatabaseService) ExecuteQuery(req *model.QueryExecuteReq, c *gin.Contex
 		SQL:        req.SQL,
 		Duration:   duration,
 		ClientIP:   c.ClientIP(),
+		Action:     model.AuditQuery,
 	}
 
 	if err != nil {
+		err = queryError(ctx, query, timeout, err)
 		// 记录失败日志
 		audit.Status = "failed"
 		audit.Error = err.Error()
 		s.db.Create(audit)
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
-	defer rows.Close()
-
-	// 获取列信息
-	columns, err := rows.Columns()
-	if err != nil {
-		audit.Status = "failed"
-		audit.Error = err.Error()
-		s.db.Create(audit)
-		return nil, fmt.Errorf("failed to get columns: %v", err)
-	}
-
-	// 准备数据容器
-	resp := &model.QueryExecuteResp{
-		Columns: columns,
-		Rows:    make([][]interface{}, 0),
-	}
-
-	// 读取数据
-	rowCount := int64(0)
-	for rows.Next() {
-		rowCount++
-		// 创建数据容器
-		values := make([]interface{}, len(columns))
-		scanArgs := make([]interface{}, len(columns))
-		for i := range values {
-			scanArgs[i] = &values[i]
-		}
-
-		// 扫描数据
-		err = rows.Scan(scanArgs...)
-		if err != nil {
-			audit.Status = "failed"
-			audit.Error = err.Error()
-			s.db.Create(audit)
-			return nil, fmt.Errorf("failed to scan row: %v", err)
-		}
-
-		// 转换数据类型
-		row := make([]interface{}, len(columns))
-		for i, v := range values {
-			if v == nil {
-				row[i] = nil
-				continue
-			}
-
-			switch v.(type) {
-			case []byte:
-				row[i] = string(v.([]byte))
-			default:
-				row[i] = v
-			}
-		}
-
-		resp.Rows = append(resp.Rows, row)
-	}
 
 	// 记录成功日志
+	resp.Duration = duration
 	audit.Status = "success"
-	audit.AffectedRows = rowCount
+	audit.AffectedRows = int64(len(resp.Rows))
 	s.db.Create(audit)
 
 	return resp, nil
 }
 
+// AnalyzeSQL 分析SQL语句，指定数据库时按数据库类型解析
+func (s *DatabaseService) AnalyzeSQL(req *model.SQLAnalyzeReq) (*model.SQLAnalysisResult, error) {
+	dbType := req.Type
+	if req.DatabaseID != 0 {
+		var dbConfig model.Database
+		if err := s.db.First(&dbConfig, req.DatabaseID).Error; err != nil {
+			return nil, fmt.Errorf("database not found: %v", err)
+		}
+		dbType = dbConfig.Type
+	}
+	return s.sqlSecurity.AnalyzeSQL(req.SQL, dbType), nil
+}
+
 // GetTables 获取表列表
 func (s *DatabaseService) GetTables(req *model.TableListReq) ([]model.TableInfo, error) {
 	db, err := s.getConnection(req.DatabaseID)
@@ -434,24 +498,53 @@ func (s *DatabaseService) GetTableSchema(req *model.TableSchemaReq) ([]model.Col
 	return columns, nil
 }
 
-// Update 更新数据库连接
+// Update 更新数据库连接，密码为空或为掩码时保留原密码
 func (s *DatabaseService) Update(req *model.DatabaseUpdateReq) error {
-	id := req.ID
+	var current model.Database
+	if err := s.db.First(&current, req.ID).Error; err != nil {
+		return err
+	}
 	db := model.Database{
 		Name:     req.Name,
 		Type:     req.Type,
 		Host:     req.Host,
 		Port:     req.Port,
 		Username: req.Username,
-		Password: req.Password,
 		Database: req.Database,
 	}
-	return s.db.Where("id = ?", id).Updates(&db).Error
+	if req.Password != "" && req.Password != model.PasswordMask {
+		password, err := secret.Encrypt(req.Password)
+		if err != nil {
+			return fmt.Errorf("加密密码失败: %v", err)
+		}
+		db.Password = password
+	}
+	if err := s.db.Where("id = ?", current.ID).Updates(&db).Error; err != nil {
+		return err
+	}
+	// 可以清空或置为0的字段单独更新
+	if err := s.db.Model(&current).Updates(map[string]interface{}{
+		"approver_role_ids": req.ApproverRoleIDs,
+		"query_timeout":     req.QueryTimeout,
+		"max_rows":          req.MaxRows,
+	}).Error; err != nil {
+		return err
+	}
+	s.closeConnection(current.ID)
+	return nil
 }
 
 // Delete 删除数据库连接
 func (s *DatabaseService) Delete(id string) error {
-	return s.db.Delete(&model.Database{}, id).Error
+	var db model.Database
+	if err := s.db.First(&db, id).Error; err != nil {
+		return err
+	}
+	if err := s.db.Delete(&db).Error; err != nil {
+		return err
+	}
+	s.closeConnection(db.ID)
+	return nil
 }
 
 // TestConnectionByID 根据ID测试数据库连接
@@ -460,14 +553,60 @@ func (s *DatabaseService) TestConnectionByID(id string) error {
 	if err := s.db.First(&db, id).Error; err != nil {
 		return err
 	}
+	password, err := s.storedPassword(&db)
+	if err != nil {
+		return err
+	}
 
 	req := &model.DatabaseTestReq{
 		Type:     db.Type,
 		Host:     db.Host,
 		Port:     db.Port,
 		Username: db.Username,
-		Password: db.Password,
+		Password: password,
 		Database: db.Database,
 	}
 	return s.TestConnection(req)
 }
+
+// RotatePasswords 用当前主密钥重新加密所有数据库连接密码，未加密的历史密码同时被加密。
+// 轮换主密钥时先将旧密钥加入 previous_master_keys，执行后再移除
+func (s *DatabaseService) RotatePasswords() (*model.SecretRotationResult, error) {
+	ring, err := secret.Default()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []model.Database
+	if err := s.db.Select("id", "password").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	result := &model.SecretRotationResult{Total: len(list)}
+	for i := range list {
+		db := &list[i]
+		if !ring.NeedsRotation(db.Password) {
+			continue
+		}
+		plaintext, err := ring.Decrypt(db.Password)
+		if err != nil {
+			log.Error(fmt.Sprintf("解密数据库连接密码失败, ID: %d, 错误: %v", db.ID, err))
+			result.Failed++
+			continue
+		}
+		encrypted, err := ring.Encrypt(plaintext)
+		if err != nil {
+			return result, err
+		}
+		// 只在密码未被并发修改时更新
+		res := s.db.Model(&model.Database{}).Where("id = ? AND password = ?", db.ID, db.Password).
+			UpdateColumn("password", encrypted)
+		if res.Error != nil {
+			return result, res.Error
+		}
+		if res.RowsAffected > 0 {
+			result.Rotated++
+		}
+	}
+	return result, nil
+}
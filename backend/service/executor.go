@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"tools-admin/backend/model"
+)
+
+// TaskExecutor 任务执行器，每种任务类型对应一个实现
+type TaskExecutor interface {
+	// Validate 校验任务内容和参数，在创建、更新任务时调用
+	Validate(task *model.Task) error
+	// Run 执行任务，ctx结束时应尽快停止并返回
+	Run(ctx context.Context, run *TaskRun) *ExecResult
+	// Cancel 取消正在进行的执行
+	Cancel(run *TaskRun) error
+}
+
+var (
+	executorsMu sync.RWMutex
+	executors   = make(map[model.TaskType]TaskExecutor)
+)
+
+// RegisterExecutor 注册任务执行器，任务类型需在 model.TaskTypeMap 中声明
+func RegisterExecutor(taskType model.TaskType, executor TaskExecutor) {
+	if _, ok := model.TaskTypeMap[taskType]; !ok {
+		panic(fmt.Sprintf("未声明的任务类型: %d", taskType))
+	}
+
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	if _, ok := executors[taskType]; ok {
+		panic(fmt.Sprintf("任务类型 %s 的执行器重复注册", taskType))
+	}
+	executors[taskType] = executor
+}
+
+// GetExecutor 获取任务类型对应的执行器
+func GetExecutor(taskType model.TaskType) (TaskExecutor, bool) {
+	executorsMu.RLock()
+	defer executorsMu.RUnlock()
+	executor, ok := executors[taskType]
+	return executor, ok
+}
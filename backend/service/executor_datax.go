@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/secret"
+)
+
+const defaultDataxChannel = 1
+
+// dataxParams DataX任务参数，来自 TaskParams
+type dataxParams struct {
+	Reader     dataxReaderParams `json:"reader"`
+	Writer     dataxWriterParams `json:"writer"`
+	Channel    int               `json:"channel"`    // 并发通道数，默认1
+	ErrorLimit int               `json:"errorLimit"` // 允许的错误记录数，默认0
+	Timeout    int               `json:"timeout"`    // 超时时间（秒），默认1小时
+}
+
+// dataxReaderParams 读取端配置
+type dataxReaderParams struct {
+	DatabaseID uint     `json:"databaseId"` // 数据库连接ID
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"` // 为空时读取全部字段
+	Where      string   `json:"where"`
+	SplitPk    string   `json:"splitPk"`
+}
+
+// dataxWriterParams 写入端配置
+type dataxWriterParams struct {
+	DatabaseID uint     `json:"databaseId"` // 数据库连接ID
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`   // 为空时与读取端一致
+	WriteMode  string   `json:"writeMode"` // 仅MySQL支持: insert/replace/update
+	PreSQL     []string `json:"preSql"`
+	PostSQL    []string `json:"postSql"`
+}
+
+// DataxStats DataX执行结束后的统计信息
+type DataxStats struct {
+	RecordsRead      int64  `json:"recordsRead"`      // 读出记录总数
+	RecordsSucceeded int64  `json:"recordsSucceeded"` // 成功记录数，由读出记录总数减去读写失败总数得出，DataX不输出写入记录数
+	ErrorRecords     int64  `json:"errorRecords"`     // 读写失败总数
+	RecordSpeed      string `json:"recordSpeed"`      // 记录写入速度
+	ByteSpeed        string `json:"byteSpeed"`        // 任务平均流量
+	TotalTime        string `json:"totalTime"`        // 任务总计耗时
+}
+
+// dataxExecutor DataX数据同步执行器
+type dataxExecutor struct{}
+
+func init() {
+	RegisterExecutor(model.TaskTypeDatax, &dataxExecutor{})
+}
+
+// Validate 校验DataX参数及引用的数据库连接
+func (e *dataxExecutor) Validate(task *model.Task) error {
+	params, err := e.parseParams(task)
+	if err != nil {
+		return err
+	}
+	if _, err := e.loadDatabase(params.Reader.DatabaseID); err != nil {
+		return err
+	}
+	_, err = e.loadDatabase(params.Writer.DatabaseID)
+	return err
+}
+
+// Cancel 取消执行，runProcess 会在ctx结束时结束DataX子进程
+func (e *dataxExecutor) Cancel(run *TaskRun) error {
+	run.cancel()
+	return nil
+}
+
+// parseParams 解析DataX任务参数
+func (e *dataxExecutor) parseParams(task *model.Task) (*dataxParams, error) {
+	params := &dataxParams{}
+	if err := json.Unmarshal([]byte(task.TaskParams), params); err != nil {
+		return nil, fmt.Errorf("DataX任务参数格式错误: %v", err)
+	}
+	if params.Reader.DatabaseID == 0 || params.Reader.Table == "" {
+		return nil, fmt.Errorf("DataX任务缺少读取端数据库或表")
+	}
+	if params.Writer.DatabaseID == 0 || params.Writer.Table == "" {
+		return nil, fmt.Errorf("DataX任务缺少写入端数据库或表")
+	}
+	if params.Channel <= 0 {
+		params.Channel = defaultDataxChannel
+	}
+	if params.Timeout < 0 {
+		return nil, fmt.Errorf("超时时间不能为负数")
+	}
+	return params, nil
+}
+
+// Run 生成DataX作业文件并执行 datax.py
+func (e *dataxExecutor) Run(ctx context.Context, run *TaskRun) *ExecResult {
+	task := run.Task
+	params, err := e.parseParams(task)
+	if err != nil {
+		return failedResult("%v", err)
+	}
+
+	job, err := e.buildJob(params)
+	if err != nil {
+		return failedResult("%v", err)
+	}
+
+	// 作业文件包含数据库密码，仅在本次执行期间保留
+	runDir, err := os.MkdirTemp("", fmt.Sprintf("datax-%d-", task.ID))
+	if err != nil {
+		return failedResult("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+
+	jobPath := filepath.Join(runDir, "job.json")
+	if err := os.WriteFile(jobPath, job, 0600); err != nil {
+		return failedResult("写入DataX作业文件失败: %v", err)
+	}
+
+	timeout := defaultTaskTimeout
+	if params.Timeout > 0 {
+		timeout = time.Duration(params.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dataxConfig := config.Config.Datax
+	if dataxConfig.Bin == "" {
+		return failedResult("未配置datax.py路径")
+	}
+	python := dataxConfig.Python
+	if python == "" {
+		python = "python3"
+	}
+	cmd := exec.CommandContext(ctx, python, dataxConfig.Bin, jobPath)
+	cmd.Dir = runDir
+
+	result := runProcess(ctx, run, cmd, timeout)
+	if stats := parseDataxStats(result.Output); stats != nil {
+		metrics, _ := json.Marshal(stats)
+		result.Metrics = string(metrics)
+	}
+	return result
+}
+
+// buildJob 根据已登记的数据库连接生成DataX作业JSON
+func (e *dataxExecutor) buildJob(params *dataxParams) ([]byte, error) {
+	readerDb, err := e.loadDatabase(params.Reader.DatabaseID)
+	if err != nil {
+		return nil, err
+	}
+	writerDb, err := e.loadDatabase(params.Writer.DatabaseID)
+	if err != nil {
+		return nil, err
+	}
+	readerPassword, err := secret.Decrypt(readerDb.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密读取端数据库密码失败: %v", err)
+	}
+	writerPassword, err := secret.Decrypt(writerDb.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密写入端数据库密码失败: %v", err)
+	}
+
+	readerColumns := params.Reader.Columns
+	if len(readerColumns) == 0 {
+		readerColumns = []string{"*"}
+	}
+	writerColumns := params.Writer.Columns
+	if len(writerColumns) == 0 {
+		writerColumns = readerColumns
+	}
+
+	readerParameter := map[string]interface{}{
+		"username": readerDb.Username,
+		"password": readerPassword,
+		"column":   readerColumns,
+		"connection": []map[string]interface{}{{
+			"table":   []string{params.Reader.Table},
+			"jdbcUrl": []string{jdbcURL(readerDb)},
+		}},
+	}
+	if params.Reader.Where != "" {
+		readerParameter["where"] = params.Reader.Where
+	}
+	if params.Reader.SplitPk != "" {
+		readerParameter["splitPk"] = params.Reader.SplitPk
+	}
+
+	writerParameter := map[string]interface{}{
+		"username": writerDb.Username,
+		"password": writerPassword,
+		"column":   writerColumns,
+		"connection": []map[string]interface{}{{
+			"table":   []string{params.Writer.Table},
+			"jdbcUrl": jdbcURL(writerDb),
+		}},
+	}
+	if writerDb.Type == "mysql" {
+		writeMode := params.Writer.WriteMode
+		if writeMode == "" {
+			writeMode = "insert"
+		}
+		writerParameter["writeMode"] = writeMode
+	}
+	if len(params.Writer.PreSQL) > 0 {
+		writerParameter["preSql"] = params.Writer.PreSQL
+	}
+	if len(params.Writer.PostSQL) > 0 {
+		writerParameter["postSql"] = params.Writer.PostSQL
+	}
+
+	job := map[string]interface{}{
+		"job": map[string]interface{}{
+			"setting": map[string]interface{}{
+				"speed":      map[string]interface{}{"channel": params.Channel},
+				"errorLimit": map[string]interface{}{"record": params.ErrorLimit},
+			},
+			"content": []map[string]interface{}{{
+				"reader": map[string]interface{}{
+					"name":      readerDb.Type + "reader",
+					"parameter": readerParameter,
+				},
+				"writer": map[string]interface{}{
+					"name":      writerDb.Type + "writer",
+					"parameter": writerParameter,
+				},
+			}},
+		},
+	}
+	return json.MarshalIndent(job, "", "  ")
+}
+
+// loadDatabase 从连接登记表中读取数据库连接
+func (e *dataxExecutor) loadDatabase(id uint) (*model.Database, error) {
+	var database model.Database
+	if err := db.Db.First(&database, id).Error; err != nil {
+		return nil, fmt.Errorf("数据库连接不存在, ID: %d, 错误: %v", id, err)
+	}
+	if database.Type != "mysql" && database.Type != "postgresql" {
+		return nil, fmt.Errorf("DataX不支持的数据库类型: %s", database.Type)
+	}
+	return &database, nil
+}
+
+// jdbcURL 生成JDBC连接地址
+func jdbcURL(database *model.Database) string {
+	if database.Type == "postgresql" {
+		return fmt.Sprintf("jdbc:postgresql://%s:%d/%s", database.Host, database.Port, database.Database)
+	}
+	return fmt.Sprintf("jdbc:mysql://%s:%d/%s?useUnicode=true&characterEncoding=utf8", database.Host, database.Port, database.Database)
+}
+
+var dataxStatPattern = regexp.MustCompile(`(?m)^[ \t]*(任务总计耗时|任务平均流量|记录写入速度|读出记录总数|读写失败总数)[ \t]*:[ \t]*(\S+)[ \t]*\r?$`)
+
+// parseDataxStats 解析DataX输出末尾的统计信息，未找到时返回nil
+func parseDataxStats(output string) *DataxStats {
+	matches := dataxStatPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	stats := &DataxStats{}
+	for _, m := range matches {
+		value := strings.TrimSpace(m[2])
+		switch m[1] {
+		case "任务总计耗时":
+			stats.TotalTime = value
+		case "任务平均流量":
+			stats.ByteSpeed = value
+		case "记录写入速度":
+			stats.RecordSpeed = value
+		case "读出记录总数":
+			stats.RecordsRead, _ = strconv.ParseInt(value, 10, 64)
+		case "读写失败总数":
+			stats.ErrorRecords, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	stats.RecordsSucceeded = stats.RecordsRead - stats.ErrorRecords
+	return stats
+}
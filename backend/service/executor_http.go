@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/jsonpath"
+)
+
+const (
+	defaultHttpTimeout = 30 * time.Second
+	maxHttpBodySize    = 1 << 20 // 断言时最多读取1MB响应体
+	maxHttpBodyLogSize = 4 << 10 // 日志中最多记录4KB响应体
+	httpAuthTypeBasic  = "basic"
+	httpAuthTypeBearer = "bearer"
+)
+
+// httpTaskSpec HTTP任务定义，来自 TaskContent
+type httpTaskSpec struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Auth    *httpAuth         `json:"auth"`
+	Timeout int               `json:"timeout"` // 超时时间（秒），默认30秒
+	Assert  *httpAssert       `json:"assert"`
+}
+
+// httpAuth HTTP认证配置
+type httpAuth struct {
+	Type     string `json:"type"` // basic/bearer
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// httpAssert 响应断言，未配置时以2xx状态码视为成功
+type httpAssert struct {
+	Status       []int   `json:"status"`       // 允许的状态码
+	BodyContains string  `json:"bodyContains"` // 响应体需包含的内容
+	JSONPath     string  `json:"jsonPath"`     // 响应体JSONPath
+	JSONValue    *string `json:"jsonValue"`    // JSONPath期望值，为空时只要求路径存在
+}
+
+// httpExecutor HTTP请求执行器
+type httpExecutor struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterExecutor(model.TaskTypeHttp, &httpExecutor{})
+}
+
+// Validate 校验HTTP任务定义
+func (e *httpExecutor) Validate(task *model.Task) error {
+	spec, err := e.parseSpec(task)
+	if err != nil {
+		return err
+	}
+	// 地址中含模板变量时在执行前渲染，此时无法校验，渲染后的地址在发送请求时校验
+	if !strings.Contains(spec.URL, templateLeftDelim) {
+		if _, err := http.NewRequest(spec.Method, spec.URL, nil); err != nil {
+			return fmt.Errorf("无效的请求地址: %v", err)
+		}
+	}
+	if spec.Assert != nil && spec.Assert.JSONPath != "" && !strings.HasPrefix(spec.Assert.JSONPath, "$") {
+		return fmt.Errorf("JSONPath必须以$开头: %s", spec.Assert.JSONPath)
+	}
+	return nil
+}
+
+// Cancel 取消执行，中断请求上下文
+func (e *httpExecutor) Cancel(run *TaskRun) error {
+	run.cancel()
+	return nil
+}
+
+// parseSpec 解析HTTP任务定义
+func (e *httpExecutor) parseSpec(task *model.Task) (*httpTaskSpec, error) {
+	spec := &httpTaskSpec{}
+	if err := json.Unmarshal([]byte(task.TaskContent), spec); err != nil {
+		return nil, fmt.Errorf("HTTP任务内容格式错误: %v", err)
+	}
+	if spec.URL == "" {
+		return nil, fmt.Errorf("HTTP任务缺少url")
+	}
+	if spec.Method == "" {
+		spec.Method = http.MethodGet
+	}
+	spec.Method = strings.ToUpper(spec.Method)
+	if spec.Auth != nil && spec.Auth.Type != httpAuthTypeBasic && spec.Auth.Type != httpAuthTypeBearer {
+		return nil, fmt.Errorf("不支持的认证类型: %s", spec.Auth.Type)
+	}
+	if spec.Timeout < 0 {
+		return nil, fmt.Errorf("超时时间不能为负数")
+	}
+	return spec, nil
+}
+
+// Run 发送HTTP请求并对响应进行断言
+func (e *httpExecutor) Run(ctx context.Context, run *TaskRun) *ExecResult {
+	spec, err := e.parseSpec(run.Task)
+	if err != nil {
+		return failedResult("%v", err)
+	}
+
+	timeout := defaultHttpTimeout
+	if spec.Timeout > 0 {
+		timeout = time.Duration(spec.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, spec.Method, spec.URL, strings.NewReader(spec.Body))
+	if err != nil {
+		return failedResult("创建请求失败: %v", err)
+	}
+	for k, v := range spec.Headers {
+		req.Header.Set(k, v)
+	}
+	if spec.Auth != nil {
+		switch spec.Auth.Type {
+		case httpAuthTypeBasic:
+			req.SetBasicAuth(spec.Auth.Username, spec.Auth.Password)
+		case httpAuthTypeBearer:
+			req.Header.Set("Authorization", "Bearer "+spec.Auth.Token)
+		}
+	}
+
+	client := e.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result := failedResult("请求超时（%s）", timeout)
+			result.FailureKind = model.FailureKindTimeout
+			return result
+		}
+		return failedResult("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHttpBodySize))
+	if err != nil {
+		return failedResult("读取响应失败: %v", err)
+	}
+
+	result := &ExecResult{
+		Status: model.TaskExecStatusSuccess,
+		Output: formatHttpResponse(resp, body),
+	}
+	if err := e.check(spec.Assert, resp.StatusCode, body); err != nil {
+		result.Status = model.TaskExecStatusFailed
+		result.Error = err.Error()
+		result.ExitCode = 1
+		result.FailureKind = model.FailureKindOther
+		if resp.StatusCode >= 500 {
+			result.FailureKind = model.FailureKindHttp5xx
+		}
+	}
+	return result
+}
+
+// check 校验响应是否满足断言
+func (e *httpExecutor) check(assert *httpAssert, statusCode int, body []byte) error {
+	if assert == nil || len(assert.Status) == 0 {
+		if statusCode < 200 || statusCode >= 300 {
+			return fmt.Errorf("响应状态码异常: %d", statusCode)
+		}
+	} else if !containsInt(assert.Status, statusCode) {
+		return fmt.Errorf("响应状态码 %d 不在期望范围 %v 内", statusCode, assert.Status)
+	}
+	if assert == nil {
+		return nil
+	}
+
+	if assert.BodyContains != "" && !strings.Contains(string(body), assert.BodyContains) {
+		return fmt.Errorf("响应体不包含期望内容: %s", assert.BodyContains)
+	}
+
+	if assert.JSONPath != "" {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return fmt.Errorf("响应体不是合法的JSON: %v", err)
+		}
+		value, err := jsonpath.Get(data, assert.JSONPath)
+		if err != nil {
+			return err
+		}
+		if assert.JSONValue != nil && stringifyJSONValue(value) != *assert.JSONValue {
+			return fmt.Errorf("JSONPath %s 的值为 %s，期望 %s", assert.JSONPath, stringifyJSONValue(value), *assert.JSONValue)
+		}
+	}
+	return nil
+}
+
+// formatHttpResponse 将响应状态、响应头和截断后的响应体格式化为日志输出
+func formatHttpResponse(resp *http.Response, body []byte) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s\n", resp.Proto, resp.Status))
+
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", k, strings.Join(resp.Header[k], ", ")))
+	}
+
+	sb.WriteString("\n")
+	if len(body) > maxHttpBodyLogSize {
+		sb.Write(body[:maxHttpBodyLogSize])
+		sb.WriteString("\n...(响应体过长，已截断)")
+	} else {
+		sb.Write(body)
+	}
+	return sb.String()
+}
+
+// stringifyJSONValue 将JSON值转换为字符串用于比较
+func stringifyJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func containsInt(list []int, target int) bool {
+	for _, v := range list {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"tools-admin/backend/model"
+)
+
+const (
+	defaultTaskTimeout = time.Hour
+	maxTaskOutputSize  = 4 << 20   // 单个输出流最多保留开头4MB
+	maxTaskOutputTail  = 256 << 10 // 超出后另外保留末尾256KB，DataX等统计信息输出在末尾
+)
+
+// ExecResult 任务执行结果
+type ExecResult struct {
+	Status   model.TaskExecStatus
+	Output   string
+	Error    string
+	ExitCode int
+	Metrics  string // 执行指标(JSON)
+
+	FailureKind string // 失败类型，见 model.FailureKind*
+}
+
+// failedResult 构造未能开始执行时的失败结果
+func failedResult(format string, args ...interface{}) *ExecResult {
+	return &ExecResult{
+		Status:      model.TaskExecStatusFailed,
+		Error:       fmt.Sprintf(format, args...),
+		ExitCode:    -1,
+		FailureKind: model.FailureKindOther,
+	}
+}
+
+// runProcess 在独立进程组中运行命令并收集输出，输出同时按行实时发布；
+// ctx结束时结束整个进程树
+func runProcess(ctx context.Context, run *TaskRun, cmd *exec.Cmd, timeout time.Duration) *ExecResult {
+	stdout := &limitedBuffer{limit: maxTaskOutputSize, tailLimit: maxTaskOutputTail, tee: newLineWriter(run, outputStreamStdout)}
+	stderr := &limitedBuffer{limit: maxTaskOutputSize, tailLimit: maxTaskOutputTail, tee: newLineWriter(run, outputStreamStderr)}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// 独立进程组，取消时结束整个进程树
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	result := &ExecResult{
+		Status:   model.TaskExecStatusSuccess,
+		Output:   stdout.String(),
+		Error:    stderr.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+	if err == nil {
+		return result
+	}
+
+	result.Status = model.TaskExecStatusFailed
+	var exitErr *exec.ExitError
+	var message string
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.FailureKind = model.FailureKindTimeout
+		message = fmt.Sprintf("执行超时（%s），已结束进程组", timeout)
+	case errors.As(err, &exitErr):
+		result.FailureKind = model.FailureKindExitCode
+		message = fmt.Sprintf("进程退出码: %d", exitErr.ExitCode())
+	default:
+		result.FailureKind = model.FailureKindOther
+		message = fmt.Sprintf("执行失败: %v", err)
+	}
+	result.Error = appendLine(result.Error, message)
+	run.output.emit(run.Attempt(), outputStreamStderr, message)
+	return result
+}
+
+// truncatedMarker 输出超出限制时插入在开头与末尾之间的提示
+const truncatedMarker = "...(输出过长，中间部分已省略)"
+
+// limitedBuffer 限制大小的输出缓冲：保留开头limit字节，超出后另外保留最近的tailLimit字节，中间部分丢弃。
+// 开头部分同时写入tee；末尾部分在Flush时写入tee，保证实时输出与持久化的输出一致
+type limitedBuffer struct {
+	buf       strings.Builder
+	limit     int
+	tail      []byte
+	tailLimit int
+	truncated bool
+	tee       *lineWriter
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	kept := p
+	if remain := b.limit - b.buf.Len(); len(p) > remain {
+		kept = p[:max(remain, 0)]
+	}
+	b.buf.Write(kept)
+	if b.tee != nil {
+		b.tee.Write(kept)
+	}
+
+	if len(kept) < len(p) {
+		if !b.truncated {
+			b.truncated = true
+			if b.tee != nil {
+				b.tee.Flush()
+				b.tee.run.output.emit(b.tee.attempt, b.tee.stream, truncatedMarker)
+			}
+		}
+		// 超过两倍上限时才收缩，避免每次写入都复制
+		b.tail = append(b.tail, p[len(kept):]...)
+		if len(b.tail) > 2*b.tailLimit {
+			b.tail = append(b.tail[:0], b.tail[len(b.tail)-b.tailLimit:]...)
+		}
+	}
+	return len(p), nil
+}
+
+// tailText 返回保留的末尾输出，从第一个完整行开始
+func (b *limitedBuffer) tailText() string {
+	tail := b.tail
+	if len(tail) > b.tailLimit {
+		tail = tail[len(tail)-b.tailLimit:]
+	}
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return string(tail)
+}
+
+// Flush 发布最后一行未换行的输出，截断时先发布保留的末尾输出
+func (b *limitedBuffer) Flush() {
+	if b.tee == nil {
+		return
+	}
+	if b.truncated {
+		b.tee.Write([]byte(b.tailText()))
+	}
+	b.tee.Flush()
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return appendLine(appendLine(b.buf.String(), truncatedMarker+"\n"), b.tailText())
+	}
+	return b.buf.String()
+}
+
+// appendLine 追加一行文本
+func appendLine(text, line string) string {
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return text + line
+	}
+	return text + "\n" + line
+}
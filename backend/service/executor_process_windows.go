@@ -0,0 +1,18 @@
+//go:build windows
+
+package service
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup Windows下不支持进程组，保持默认行为
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup Windows下只结束子进程本身
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
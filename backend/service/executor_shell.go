@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tools-admin/backend/model"
+)
+
+const defaultShell = "/bin/sh"
+
+// shellEnvAllowlist 从服务进程继承给脚本的环境变量，其余变量(主密钥、数据库和Redis的连接信息等)不传给脚本
+var shellEnvAllowlist = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// shellParams Shell任务参数，来自 TaskParams
+type shellParams struct {
+	Shell   string            `json:"shell"`   // 解释器，默认 /bin/sh
+	WorkDir string            `json:"workDir"` // 工作目录，默认为本次执行的临时目录
+	Env     map[string]string `json:"env"`     // 环境变量
+	Timeout int               `json:"timeout"` // 超时时间（秒），默认1小时
+}
+
+// shellExecutor Shell脚本执行器
+type shellExecutor struct{}
+
+func init() {
+	RegisterExecutor(model.TaskTypeShell, &shellExecutor{})
+}
+
+// Validate 校验脚本内容和参数
+func (e *shellExecutor) Validate(task *model.Task) error {
+	if strings.TrimSpace(task.TaskContent) == "" {
+		return fmt.Errorf("脚本内容不能为空")
+	}
+	_, err := e.parseParams(task)
+	return err
+}
+
+// Cancel 取消执行，runProcess 会在ctx结束时结束整个进程组
+func (e *shellExecutor) Cancel(run *TaskRun) error {
+	run.cancel()
+	return nil
+}
+
+// parseParams 解析Shell任务参数
+func (e *shellExecutor) parseParams(task *model.Task) (*shellParams, error) {
+	params := &shellParams{}
+	if strings.TrimSpace(task.TaskParams) != "" {
+		if err := json.Unmarshal([]byte(task.TaskParams), params); err != nil {
+			return nil, fmt.Errorf("任务参数格式错误: %v", err)
+		}
+	}
+	if params.Shell == "" {
+		params.Shell = defaultShell
+	}
+	if params.Timeout < 0 {
+		return nil, fmt.Errorf("超时时间不能为负数")
+	}
+	return params, nil
+}
+
+// Run 在独立进程组中执行脚本，超时后结束整个进程树
+func (e *shellExecutor) Run(ctx context.Context, run *TaskRun) *ExecResult {
+	task := run.Task
+	params, err := e.parseParams(task)
+	if err != nil {
+		return failedResult("%v", err)
+	}
+
+	// 脚本写入本次执行的临时目录
+	runDir, err := os.MkdirTemp("", fmt.Sprintf("task-%d-", task.ID))
+	if err != nil {
+		return failedResult("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+
+	scriptPath := filepath.Join(runDir, "script.sh")
+	if err := os.WriteFile(scriptPath, []byte(task.TaskContent), 0700); err != nil {
+		return failedResult("写入脚本失败: %v", err)
+	}
+
+	timeout := defaultTaskTimeout
+	if params.Timeout > 0 {
+		timeout = time.Duration(params.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, params.Shell, scriptPath)
+	cmd.Dir = runDir
+	if params.WorkDir != "" {
+		cmd.Dir = params.WorkDir
+	}
+	cmd.Env = shellEnv(task, params.Env)
+
+	return runProcess(ctx, run, cmd, timeout)
+}
+
+// shellEnv 脚本的环境变量：白名单中的服务进程变量、任务信息和任务参数中配置的变量
+func shellEnv(task *model.Task, extra map[string]string) []string {
+	env := make([]string, 0, len(shellEnvAllowlist)+2+len(extra))
+	for _, key := range shellEnvAllowlist {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	env = append(env,
+		fmt.Sprintf("TASK_ID=%d", task.ID),
+		fmt.Sprintf("TASK_NAME=%s", task.Name),
+	)
+	for k, v := range extra {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
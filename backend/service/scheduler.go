@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/cronutil"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler 任务调度器，按cron表达式触发已启动的任务
+type Scheduler struct {
+	cron        *cron.Cron
+	taskService *TaskService
+	mu          sync.Mutex
+	entries     map[uint]cron.EntryID // taskID -> cron条目
+	depMu       sync.Mutex            // 串行化下游依赖检查，避免重复触发
+	elector     *leaderElector        // 主节点选举，只有主节点触发定时调度
+}
+
+// TaskScheduler 全局任务调度器
+var TaskScheduler = NewScheduler()
+
+// NewScheduler 创建调度器实例
+func NewScheduler() *Scheduler {
+	s := &Scheduler{
+		cron:        cron.New(cron.WithParser(cronutil.Parser)),
+		taskService: &TaskService{},
+		entries:     make(map[uint]cron.EntryID),
+		elector:     newLeaderElector(),
+	}
+	// 成为主节点时处理错过的调度
+	s.elector.onElected = s.reconcileMisfires
+	return s
+}
+
+// Start 加载所有已启动的任务并开始调度，成为主节点后按策略处理停机期间错过的调度
+func (s *Scheduler) Start() error {
+	var tasks []*model.Task
+	if err := db.Db.Where("status = ?", model.TaskStatusStarted).Find(&tasks).Error; err != nil {
+		log.Error(fmt.Sprintf("加载已启动任务失败: %v", err))
+		return err
+	}
+
+	for _, task := range tasks {
+		if err := s.Schedule(task); err != nil {
+			log.Error(fmt.Sprintf("注册任务失败, ID: %d, 错误: %v", task.ID, err))
+		}
+	}
+
+	s.elector.start()
+	s.cron.Start()
+	log.Info(fmt.Sprintf("任务调度器启动成功，已注册 %d 个任务", len(s.entries)))
+	return nil
+}
+
+// Stop 停止调度并退出主节点选举，返回的context在正在执行的任务结束后关闭
+func (s *Scheduler) Stop() context.Context {
+	ctx := s.cron.Stop()
+	s.elector.stop()
+	return ctx
+}
+
+// Schedule 注册任务，已注册的任务会按最新配置重新注册；
+// 未启动或没有cron表达式的任务只会被移除
+func (s *Scheduler) Schedule(task *model.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(task.ID)
+	if task.Status != model.TaskStatusStarted || task.CronExpr == "" {
+		return nil
+	}
+
+	schedule, err := taskSchedule(task)
+	if err != nil {
+		return err
+	}
+
+	taskID := task.ID
+	s.entries[taskID] = s.cron.Schedule(schedule, cron.FuncJob(func() {
+		s.fire(taskID)
+	}))
+	log.Info(fmt.Sprintf("任务已注册到调度器, ID: %d, cron: %s", taskID, task.CronExpr))
+	return nil
+}
+
+// Reschedule 从数据库重新加载任务并注册，任务启动时从当前时间重新计算下次执行时间，
+// 停止期间的触发不视为错过的调度
+func (s *Scheduler) Reschedule(taskID uint) error {
+	var task model.Task
+	if err := db.Db.First(&task, taskID).Error; err != nil {
+		s.Remove(taskID)
+		return err
+	}
+	if task.Status == model.TaskStatusStarted && task.CronExpr != "" {
+		nextRunTime, err := taskNextRunTime(&task, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := db.Db.Model(&task).Update("next_run_time", nextRunTime).Error; err != nil {
+			return err
+		}
+	}
+	return s.Schedule(&task)
+}
+
+// Remove 移除任务
+func (s *Scheduler) Remove(taskID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(taskID)
+}
+
+func (s *Scheduler) removeLocked(taskID uint) {
+	if entryID, ok := s.entries[taskID]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, taskID)
+		log.Info(fmt.Sprintf("任务已从调度器移除, ID: %d", taskID))
+	}
+}
+
+// fire 执行一次调度触发，并更新任务的下次执行时间
+func (s *Scheduler) fire(taskID uint) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("调度任务异常, ID: %d, 错误: %v", taskID, r))
+		}
+	}()
+
+	// 多副本部署时只有主节点触发，且同一次触发只会被认领一次
+	scheduledTime := time.Now().Truncate(time.Second)
+	if !s.elector.isLeader() || !s.elector.claimFire(taskID, scheduledTime) {
+		return
+	}
+
+	task, err := s.taskService.GetByID(taskID)
+	if err != nil {
+		s.Remove(taskID)
+		return
+	}
+	if task.Status != model.TaskStatusStarted {
+		s.Remove(taskID)
+		return
+	}
+
+	// 提交到执行池，上次执行时间在开始执行时记录
+	if _, err := s.taskService.dispatch(task, scheduledTime, nil); err != nil {
+		log.Info(fmt.Sprintf("任务本次调度未执行, ID: %d, 原因: %v", taskID, err))
+	}
+
+	nextRunTime, err := taskNextRunTime(task, time.Now())
+	if err != nil {
+		log.Error(fmt.Sprintf("计算下次执行时间失败, ID: %d, 错误: %v", taskID, err))
+	}
+	if err := db.Db.Model(&model.Task{}).Where("id = ?", taskID).Update("next_run_time", nextRunTime).Error; err != nil {
+		log.Error(fmt.Sprintf("更新任务执行时间失败, ID: %d, 错误: %v", taskID, err))
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/pkg/log"
+	"tools-admin/backend/pkg/redis"
+)
+
+const (
+	defaultLeaseTTL = 15 * time.Second
+	fireClaimTTL    = 10 * time.Minute // 单次调度触发记录的保留时间
+)
+
+// leaderElector 基于Redis租约的调度主节点选举。多副本部署时只有主节点触发定时调度，
+// 主节点宕机后租约最迟在ttl后过期，由其他副本接管
+type leaderElector struct {
+	enabled bool
+	lease   *redis.Lease
+	ttl     time.Duration
+	owner   string
+
+	onElected func() // 成为主节点时调用，未开启选举时在启动时调用
+
+	leader      atomic.Bool
+	lastRenewed time.Time
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+}
+
+// newLeaderElector 根据配置创建选举器，未开启选举时当前实例始终为主节点
+func newLeaderElector() *leaderElector {
+	cfg := config.Config.Scheduler
+	e := &leaderElector{
+		enabled: cfg.LeaderElection,
+		ttl:     defaultLeaseTTL,
+		owner:   newInstanceID(),
+		stopCh:  make(chan struct{}),
+	}
+	if cfg.LeaseTTL > 0 {
+		e.ttl = time.Duration(cfg.LeaseTTL) * time.Second
+	}
+	if e.enabled {
+		key := fmt.Sprintf("%s:scheduler:leader", config.Config.Server.Name)
+		e.lease = redis.Redis.NewLease(key, e.owner, e.ttl)
+	}
+	return e
+}
+
+// newInstanceID 生成实例标识：主机名、进程号加随机数
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// start 开始参与选举，每隔ttl/3尝试获取或续约租约
+func (e *leaderElector) start() {
+	if !e.enabled {
+		e.leader.Store(true)
+		if e.onElected != nil {
+			e.onElected()
+		}
+		return
+	}
+
+	log.Info(fmt.Sprintf("调度主节点选举已开启, 实例: %s, 租约: %s", e.owner, e.ttl))
+	e.tick()
+	go func() {
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stopCh:
+				return
+			case <-ticker.C:
+				e.tick()
+			}
+		}
+	}()
+}
+
+// stop 退出选举并释放租约，便于其他副本立即接管
+func (e *leaderElector) stop() {
+	if !e.enabled {
+		return
+	}
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+		if e.leader.Swap(false) {
+			if err := e.lease.Release(); err != nil {
+				log.Error(fmt.Sprintf("释放调度主节点租约失败: %v", err))
+			}
+		}
+	})
+}
+
+func (e *leaderElector) tick() {
+	if e.leader.Load() {
+		ok, err := e.lease.Renew()
+		switch {
+		case err == nil && ok:
+			e.lastRenewed = time.Now()
+		case err == nil:
+			e.leader.Store(false)
+			log.Error(fmt.Sprintf("调度主节点租约已被其他实例获取, 实例: %s", e.owner))
+		case time.Since(e.lastRenewed) >= e.ttl:
+			// 续约持续失败，租约可能已过期，主动退出
+			e.leader.Store(false)
+			log.Error(fmt.Sprintf("调度主节点续约失败，退出主节点, 实例: %s, 错误: %v", e.owner, err))
+		default:
+			log.Error(fmt.Sprintf("调度主节点续约失败, 实例: %s, 错误: %v", e.owner, err))
+		}
+		return
+	}
+
+	ok, err := e.lease.Acquire()
+	if err != nil {
+		log.Error(fmt.Sprintf("获取调度主节点租约失败: %v", err))
+		return
+	}
+	if ok {
+		e.lastRenewed = time.Now()
+		e.leader.Store(true)
+		log.Info(fmt.Sprintf("当前实例成为调度主节点, 实例: %s, fencing token: %d", e.owner, e.lease.Token()))
+		if e.onElected != nil {
+			go e.onElected()
+		}
+	}
+}
+
+// isLeader 当前实例是否为主节点
+func (e *leaderElector) isLeader() bool {
+	return e.leader.Load()
+}
+
+// claimFire 认领任务在某一时刻的调度触发，同一次触发只有一个实例能认领成功；
+// 已失去租约的旧主节点因fencing token过期无法认领
+func (e *leaderElector) claimFire(taskID uint, at time.Time) bool {
+	if !e.enabled {
+		return true
+	}
+	key := fmt.Sprintf("%s:scheduler:fire:%d:%d", config.Config.Server.Name, taskID, at.Unix())
+	ok, err := e.lease.Claim(key, fireClaimTTL)
+	if err != nil {
+		log.Error(fmt.Sprintf("认领调度触发失败, ID: %d, 错误: %v", taskID, err))
+		if err == redis.ErrStaleToken {
+			e.leader.Store(false)
+		}
+		return false
+	}
+	return ok
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+)
+
+const (
+	misfireThreshold = time.Second // 下次执行时间早于当前时间超过该值视为错过调度
+	maxMisfireCount  = 100000      // 统计错过次数的上限，避免秒级任务长时间停机后遍历过久
+)
+
+// reconcileMisfires 处理停机或主节点切换期间错过的调度触发，
+// 按任务的错过调度处理策略补执行或跳过，并更新下次执行时间
+func (s *Scheduler) reconcileMisfires() {
+	now := time.Now()
+	var tasks []*model.Task
+	if err := db.Db.Where("status = ? AND cron_expr <> '' AND next_run_time < ?",
+		model.TaskStatusStarted, now.Add(-misfireThreshold)).Find(&tasks).Error; err != nil {
+		log.Error(fmt.Sprintf("加载错过调度的任务失败: %v", err))
+		return
+	}
+
+	for _, task := range tasks {
+		if err := s.handleMisfire(task, now); err != nil {
+			log.Error(fmt.Sprintf("处理错过的调度失败, ID: %d, 错误: %v", task.ID, err))
+		}
+	}
+}
+
+// handleMisfire 处理单个任务错过的调度
+func (s *Scheduler) handleMisfire(task *model.Task, now time.Time) error {
+	schedule, err := taskSchedule(task)
+	if err != nil {
+		return err
+	}
+
+	// 统计错过的触发时间，只保留最近的 MisfireLimit 次
+	limit := task.MisfireLimit
+	if limit <= 0 {
+		limit = defaultMisfireLimit
+	}
+	var missed []time.Time
+	count := 0
+	for t := *task.NextRunTime; !t.After(now) && count < maxMisfireCount; t = schedule.Next(t) {
+		count++
+		missed = append(missed, t)
+		if len(missed) > limit {
+			missed = missed[1:]
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	nextRunTime := schedule.Next(now)
+	if err := db.Db.Model(&model.Task{}).Where("id = ?", task.ID).Update("next_run_time", nextRunTime).Error; err != nil {
+		return err
+	}
+
+	first, last := *task.NextRunTime, missed[len(missed)-1]
+	summary := fmt.Sprintf("错过 %d 次调度（%s 至 %s）", count,
+		first.Format("2006-01-02 15:04:05"), last.Format("2006-01-02 15:04:05"))
+	switch task.MisfirePolicy {
+	case model.MisfireFireOnce:
+		log.Info(fmt.Sprintf("任务%s，按策略补执行一次, ID: %d", summary, task.ID))
+		if s.elector.claimFire(task.ID, last) {
+			s.taskService.dispatch(task, last, nil)
+		}
+	case model.MisfireFireAll:
+		if count > len(missed) {
+			s.taskService.saveSkippedLog(task, fmt.Sprintf("%s，超过补执行上限 %d 次，跳过较早的 %d 次", summary, limit, count-len(missed)))
+		}
+		log.Info(fmt.Sprintf("任务%s，按策略依次补执行 %d 次, ID: %d", summary, len(missed), task.ID))
+		go s.catchUp(task, missed)
+	default:
+		s.taskService.saveSkippedLog(task, fmt.Sprintf("%s，按策略跳过", summary))
+	}
+	return nil
+}
+
+// catchUp 按时间顺序依次补执行错过的调度，上一次执行结束后再开始下一次
+func (s *Scheduler) catchUp(task *model.Task, missed []time.Time) {
+	for _, scheduledTime := range missed {
+		if !s.elector.isLeader() {
+			log.Info(fmt.Sprintf("当前实例已不是调度主节点，停止补执行, ID: %d", task.ID))
+			return
+		}
+		if !s.elector.claimFire(task.ID, scheduledTime) {
+			continue
+		}
+		run, err := s.taskService.dispatch(task, scheduledTime, nil)
+		if err != nil {
+			continue
+		}
+		<-run.done
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/export"
+	"tools-admin/backend/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultExportMaxRows = 100000
+	defaultExportTimeout = 10 * time.Minute
+)
+
+// exportLimits 返回导出的超时和行数上限，XLSX不超过工作表的最大行数
+func exportLimits(format export.Format) (time.Duration, int) {
+	cfg := config.Config.SQLConsole
+	timeout := defaultExportTimeout
+	if cfg.ExportTimeout > 0 {
+		timeout = time.Duration(cfg.ExportTimeout) * time.Second
+	}
+	maxRows := defaultExportMaxRows
+	if cfg.ExportMaxRows > 0 {
+		maxRows = cfg.ExportMaxRows
+	}
+	if format == export.XLSX && maxRows > export.MaxXLSXRows {
+		maxRows = export.MaxXLSXRows
+	}
+	return timeout, maxRows
+}
+
+// ExportQuery 执行查询并将结果以文件流式写入响应，不在内存中缓存结果。
+// 开始写入响应之前出错时返回错误且未写入任何内容；开始写入之后出错时文件不完整，
+// 导出的行数、是否截断和错误信息通过HTTP Trailer返回
+func (s *DatabaseService) ExportQuery(req *model.QueryExecuteReq, c *gin.Context) error {
+	format := export.Format(req.Export)
+	if !format.Valid() {
+		return fmt.Errorf("不支持的导出格式: %s", req.Export)
+	}
+	dbConfig, err := s.prepareQuery(req)
+	if err != nil {
+		return err
+	}
+	db, err := s.getConnection(req.DatabaseID)
+	if err != nil {
+		return err
+	}
+
+	queryID := req.QueryID
+	if queryID == "" {
+		queryID = newQueryID()
+	}
+	timeout, maxRows := exportLimits(format)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	query := &runningQuery{
+		id:        queryID,
+		userID:    c.GetUint("user_id"),
+		dbType:    dbConfig.Type,
+		cancel:    cancel,
+		startedAt: startTime,
+	}
+	var writer export.Writer
+	count, truncated, err := s.streamQuery(ctx, db, query, req.SQL, maxRows, queryHandler{
+		columns: func(columns []*sql.ColumnType) error {
+			cols := make([]export.Column, len(columns))
+			for i, col := range columns {
+				cols[i] = export.Column{Name: col.Name(), Numeric: export.IsNumericType(col.DatabaseTypeName())}
+			}
+
+			// 查询成功后才开始写入响应
+			fileName := fmt.Sprintf("%s_%s%s", dbConfig.Name, startTime.Format("20060102150405"), format.Extension())
+			header := c.Writer.Header()
+			header.Set("Content-Type", format.ContentType())
+			header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+			header.Set("X-Query-Id", queryID)
+			header.Set("Trailer", "X-Export-Rows, X-Export-Truncated, X-Export-Error")
+			c.Status(http.StatusOK)
+
+			w, err := export.New(format, c.Writer)
+			if err != nil {
+				return err
+			}
+			writer = w
+			return writer.WriteHeader(cols)
+		},
+		row: func(values []interface{}) error {
+			return writer.WriteRow(values)
+		},
+	})
+	if writer != nil {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		err = queryError(ctx, query, timeout, err)
+	}
+	if writer != nil {
+		header := c.Writer.Header()
+		header.Set("X-Export-Rows", strconv.Itoa(count))
+		header.Set("X-Export-Truncated", strconv.FormatBool(truncated))
+		if err != nil {
+			header.Set("X-Export-Error", err.Error())
+		}
+	}
+
+	// 记录导出审计，AffectedRows为导出的行数
+	audit := &model.SQLAudit{
+		DatabaseID:   req.DatabaseID,
+		UserID:       c.GetUint("user_id"),
+		Username:     c.GetString("username"),
+		SQL:          req.SQL,
+		Duration:     time.Since(startTime).Milliseconds(),
+		Status:       "success",
+		AffectedRows: int64(count),
+		ClientIP:     c.ClientIP(),
+		Action:       model.AuditExport,
+	}
+	if err != nil {
+		audit.Status = "failed"
+		audit.Error = err.Error()
+	}
+	s.db.Create(audit)
+
+	if err != nil {
+		log.Error(fmt.Sprintf("导出查询结果失败, 查询ID: %s, 格式: %s, 已导出: %d行, 错误: %v", queryID, format, count, err))
+		return err
+	}
+	log.Info(fmt.Sprintf("导出查询结果, 查询ID: %s, 格式: %s, 行数: %d, 截断: %t, 用户: %s",
+		queryID, format, count, truncated, c.GetString("username")))
+	return nil
+}
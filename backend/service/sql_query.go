@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultQueryTimeout = 30 * time.Second
+	defaultMaxRows      = 1000
+	killQueryTimeout    = 5 * time.Second
+)
+
+// runningQuery 执行中的查询，用于按查询ID取消
+type runningQuery struct {
+	id        string
+	userID    uint
+	dbType    string
+	backendID int64 // 执行查询的会话ID：MySQL为CONNECTION_ID()，PostgreSQL为pg_backend_pid()
+	cancel    context.CancelFunc
+	cancelled atomic.Bool // 被用户取消
+	startedAt time.Time
+
+	// mu 使数据库端终止查询与查询结束互斥：查询结束后不再终止，终止进行中时等待其完成再释放连接
+	mu       sync.Mutex
+	finished bool // 查询已结束，会话可能已执行其他语句
+	killed   bool // 已在数据库端终止过查询
+}
+
+// newQueryID 生成查询ID
+func newQueryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// queryLimits 返回数据库连接的查询超时和行数上限，未设置时使用全局配置
+func queryLimits(dbConfig *model.Database) (time.Duration, int) {
+	cfg := config.Config.SQLConsole
+	timeout := defaultQueryTimeout
+	switch {
+	case dbConfig.QueryTimeout > 0:
+		timeout = time.Duration(dbConfig.QueryTimeout) * time.Second
+	case cfg.QueryTimeout > 0:
+		timeout = time.Duration(cfg.QueryTimeout) * time.Second
+	}
+	maxRows := defaultMaxRows
+	switch {
+	case dbConfig.MaxRows > 0:
+		maxRows = dbConfig.MaxRows
+	case cfg.MaxRows > 0:
+		maxRows = cfg.MaxRows
+	}
+	return timeout, maxRows
+}
+
+// queryHandler 处理查询结果：columns在读取数据之前调用一次，row对每一行调用。
+// row收到的值为扫描得到的原始值，调用返回后不再使用
+type queryHandler struct {
+	columns func(columns []*sql.ColumnType) error
+	row     func(values []interface{}) error
+}
+
+// streamQuery 在独立的会话上执行查询，逐行交给handler处理，最多处理maxRows行，返回处理的行数和是否截断。
+// 上下文结束(超时、取消或结果截断)时，除了断开客户端读取，还会在数据库端终止查询
+func (s *DatabaseService) streamQuery(ctx context.Context, db *sql.DB, query *runningQuery, sqlText string, maxRows int, handler queryHandler) (int, bool, error) {
+	if _, loaded := s.queries.LoadOrStore(query.id, query); loaded {
+		return 0, false, fmt.Errorf("查询ID %s 正在使用", query.id)
+	}
+	defer s.queries.Delete(query.id)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	// 会话ID获取失败时只能断开客户端读取，不影响查询本身
+	switch query.dbType {
+	case "mysql":
+		err = conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&query.backendID)
+	case "postgresql":
+		err = conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&query.backendID)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("获取查询会话ID失败, 查询ID: %s, 错误: %v", query.id, err))
+		query.backendID = 0
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		query.mu.Lock()
+		defer query.mu.Unlock()
+		if query.finished {
+			return
+		}
+		query.killed = true
+		s.killQuery(db, query)
+	})
+	// 在结果集和事务关闭之后、连接释放之前执行。终止语句可能晚于查询结束才到达数据库，
+	// 发出过终止的连接直接丢弃，不放回连接池，避免误伤之后复用该连接的查询
+	defer func() {
+		stop()
+		query.mu.Lock()
+		query.finished = true
+		killed := query.killed
+		query.mu.Unlock()
+		if killed {
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	// 在只读事务中执行：自定义函数等语法分析无法识别的修改由数据库拒绝，
+	// 结束时回滚，set_config 等对会话设置的修改也随之撤销，不会留在连接池的连接上
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, sqlText)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	// 获取列信息
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get columns: %v", err)
+	}
+	if err := handler.columns(columns); err != nil {
+		return 0, false, err
+	}
+
+	// 读取数据
+	values := make([]interface{}, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	count := 0
+	for rows.Next() {
+		if count >= maxRows {
+			// 关闭结果集会读完剩余的行，先取消查询
+			query.cancel()
+			return count, true, nil
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return count, false, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if err := handler.row(values); err != nil {
+			return count, false, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, false, err
+	}
+	return count, false, nil
+}
+
+// runQuery 执行查询并返回最多maxRows行结果
+func (s *DatabaseService) runQuery(ctx context.Context, db *sql.DB, query *runningQuery, sqlText string, maxRows int) (*model.QueryExecuteResp, error) {
+	resp := &model.QueryExecuteResp{
+		QueryID: query.id,
+		Rows:    make([][]interface{}, 0),
+		MaxRows: maxRows,
+	}
+	_, truncated, err := s.streamQuery(ctx, db, query, sqlText, maxRows, queryHandler{
+		columns: func(columns []*sql.ColumnType) error {
+			for _, col := range columns {
+				resp.Columns = append(resp.Columns, col.Name())
+			}
+			return nil
+		},
+		row: func(values []interface{}) error {
+			// 转换数据类型
+			row := make([]interface{}, len(values))
+			for i, v := range values {
+				switch v := v.(type) {
+				case []byte:
+					row[i] = string(v)
+				default:
+					row[i] = v
+				}
+			}
+			resp.Rows = append(resp.Rows, row)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Truncated = truncated
+	return resp, nil
+}
+
+// queryError 将因超时或取消而失败的查询错误转换为可读的错误信息
+func queryError(ctx context.Context, query *runningQuery, timeout time.Duration, err error) error {
+	switch {
+	case query.cancelled.Load():
+		return fmt.Errorf("查询已取消")
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("查询超时(%d秒)，已取消", int(timeout/time.Second))
+	}
+	return err
+}
+
+// killQuery 在数据库端终止查询：MySQL使用 KILL QUERY，PostgreSQL使用 pg_cancel_backend
+func (s *DatabaseService) killQuery(db *sql.DB, query *runningQuery) {
+	if query.backendID == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), killQueryTimeout)
+	defer cancel()
+
+	var err error
+	switch query.dbType {
+	case "mysql":
+		_, err = db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", query.backendID))
+	case "postgresql":
+		_, err = db.ExecContext(ctx, "SELECT pg_cancel_backend($1)", query.backendID)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("终止查询失败, 查询ID: %s, 会话ID: %d, 错误: %v", query.id, query.backendID, err))
+		return
+	}
+	log.Info(fmt.Sprintf("已终止查询, 查询ID: %s, 会话ID: %d, 已执行: %s",
+		query.id, query.backendID, time.Since(query.startedAt).Round(time.Millisecond)))
+}
+
+// CancelQuery 取消执行中的查询，只能取消自己发起的查询
+func (s *DatabaseService) CancelQuery(queryID string, c *gin.Context) error {
+	value, ok := s.queries.Load(queryID)
+	if !ok {
+		return fmt.Errorf("查询不存在或已结束")
+	}
+	query := value.(*runningQuery)
+	if query.userID != c.GetUint("user_id") {
+		return fmt.Errorf("只能取消自己发起的查询")
+	}
+	query.cancelled.Store(true)
+	query.cancel()
+	return nil
+}
@@ -2,9 +2,10 @@ package service
 
 import (
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
 	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/sqlparser"
 )
 
 // SQLSecurityService SQL安全服务
@@ -15,117 +16,225 @@ func NewSQLSecurityService() *SQLSecurityService {
 	return &SQLSecurityService{}
 }
 
-// 危险SQL关键字
-var dangerousKeywords = []string{
-	"DELETE FROM", "DROP TABLE", "DROP DATABASE", "TRUNCATE TABLE",
-	"ALTER TABLE", "UPDATE.*SET", "INSERT INTO",
+// 危险函数，可读写服务器文件、阻塞连接、访问外部资源，或在查询中修改会话和序列状态
+var dangerousFunctions = map[string]bool{
+	"SLEEP": true, "BENCHMARK": true, "LOAD_FILE": true, "GET_LOCK": true, "SYS_EXEC": true, "SYS_EVAL": true,
+	"PG_SLEEP": true, "PG_SLEEP_FOR": true, "PG_SLEEP_UNTIL": true, "PG_READ_FILE": true,
+	"PG_READ_BINARY_FILE": true, "PG_LS_DIR": true, "PG_STAT_FILE": true, "DBLINK": true, "DBLINK_EXEC": true,
+	"PG_TERMINATE_BACKEND": true, "PG_CANCEL_BACKEND": true, "PG_RELOAD_CONF": true, "PG_ADVISORY_LOCK": true,
+	"PG_ADVISORY_XACT_LOCK": true, "PG_TRY_ADVISORY_LOCK": true, "PG_NOTIFY": true,
+	"SET_CONFIG": true, "NEXTVAL": true, "SETVAL": true,
 }
 
-// 危险函数
-var dangerousFunctions = []string{
-	"SLEEP", "BENCHMARK", "LOAD_FILE", "INTO OUTFILE",
-	"INTO DUMPFILE", "INFORMATION_SCHEMA",
+// dangerousFunctionPrefixes 危险函数的前缀，如PostgreSQL读写大对象的 lo_import、lo_unlink 等
+var dangerousFunctionPrefixes = []string{"LO_"}
+
+// isDangerousFunction 是否为危险函数，函数名为大写
+func isDangerousFunction(name string) bool {
+	if dangerousFunctions[name] {
+		return true
+	}
+	for _, prefix := range dangerousFunctionPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 系统库，查询可能泄露实例信息
+var systemSchemas = map[string]bool{
+	"information_schema": true, "mysql": true, "performance_schema": true, "sys": true, "pg_catalog": true,
+}
+
+// 存放账号和密码的系统表
+var credentialTables = map[string]bool{
+	"mysql.user": true, "pg_authid": true, "pg_shadow": true, "pg_user_mapping": true,
+	"pg_catalog.pg_authid": true, "pg_catalog.pg_shadow": true, "pg_catalog.pg_user_mapping": true,
 }
 
-// 注入特征
-var injectionPatterns = []string{
-	"'.*OR.*'", "'.*AND.*'", "--.*", "/\\*.*\\*/",
-	"#.*", "UNION.*SELECT", "CONCAT\\(.*\\)",
+// 管理类语句，影响实例而不是数据
+var adminVerbs = map[string]bool{
+	"SET GLOBAL": true, "KILL": true, "SHUTDOWN": true, "FLUSH": true, "RESET": true, "PURGE": true,
+	"INSTALL": true, "UNINSTALL": true, "CHANGE": true, "STOP": true, "COPY": true, "LOCK": true,
+	"VACUUM": true, "CHECKPOINT": true, "REINDEX": true, "CLUSTER": true, "LOAD DATA": true, "LOAD XML": true,
+	"CALL": true, "DO": true, "HANDLER": true, "PREPARE": true, "EXECUTE": true, "IMPORT": true,
+	"LOAD": true, "ALTER SYSTEM": true,
 }
 
-// AnalyzeSQL 分析SQL语句安全性
-func (s *SQLSecurityService) AnalyzeSQL(sql string) *model.SQLAnalysisResult {
-	sql = strings.TrimSpace(strings.ToUpper(sql))
+var riskRank = map[model.SQLRisk]int{model.RiskLow: 1, model.RiskMedium: 2, model.RiskHigh: 3}
+
+// sqlDialect 根据数据库类型返回SQL方言，未知类型按MySQL处理
+func sqlDialect(dbType string) sqlparser.Dialect {
+	if dbType == string(sqlparser.PostgreSQL) {
+		return sqlparser.PostgreSQL
+	}
+	return sqlparser.MySQL
+}
+
+// ParseSQL 按数据库类型解析SQL
+func (s *SQLSecurityService) ParseSQL(sql, dbType string) ([]*sqlparser.Statement, error) {
+	return sqlparser.Parse(sql, sqlDialect(dbType))
+}
+
+// AnalyzeSQL 解析SQL并分析每条语句的安全风险
+func (s *SQLSecurityService) AnalyzeSQL(sql, dbType string) *model.SQLAnalysisResult {
 	result := &model.SQLAnalysisResult{
 		Risk:        model.RiskLow,
 		Description: "SQL语句安全",
-		Suggestion:  "",
+		Statements:  make([]*model.SQLStatementInfo, 0),
+		Findings:    make([]*model.SQLFinding, 0),
+	}
+	add := func(rule string, risk model.SQLRisk, index int, message, suggestion string) {
+		result.Findings = append(result.Findings, &model.SQLFinding{
+			Rule:       rule,
+			Risk:       risk,
+			Statement:  index,
+			Message:    message,
+			Suggestion: suggestion,
+		})
 	}
 
-	// 检查危险关键字
-	for _, keyword := range dangerousKeywords {
-		if matched, _ := regexp.MatchString(fmt.Sprintf("(?i)%s", keyword), sql); matched {
-			result.Risk = model.RiskHigh
-			result.Description = fmt.Sprintf("包含危险操作: %s", keyword)
-			result.Suggestion = "请确认是否需要执行数据修改操作，建议添加 WHERE 条件限制范围"
-			return result
-		}
+	statements, err := s.ParseSQL(sql, dbType)
+	switch {
+	case err != nil:
+		add("parse_error", model.RiskHigh, 0, fmt.Sprintf("SQL解析失败: %v", err), "请检查SQL语法")
+	case len(statements) == 0:
+		add("empty", model.RiskHigh, 0, "SQL语句为空", "")
+	case len(statements) > 1:
+		add("multiple_statements", model.RiskMedium, 0, fmt.Sprintf("包含%d条语句", len(statements)),
+			"请逐条执行，避免在一次请求中夹带其他语句")
 	}
 
-	// 检查危险函数
-	for _, function := range dangerousFunctions {
-		if matched, _ := regexp.MatchString(fmt.Sprintf("(?i)%s", function), sql); matched {
-			result.Risk = model.RiskHigh
-			result.Description = fmt.Sprintf("包含危险函数: %s", function)
-			result.Suggestion = "请避免使用系统敏感函数"
-			return result
+	for i, stmt := range statements {
+		index := i + 1
+		info := &model.SQLStatementInfo{
+			SQL:      stmt.Text,
+			Type:     string(stmt.Type),
+			Verb:     stmt.Verb,
+			Tables:   stmt.Tables,
+			Writes:   make([]string, 0, len(stmt.Writes)),
+			HasWhere: stmt.HasWhere,
+			HasLimit: stmt.HasLimit,
+			Locking:  stmt.Locking,
+		}
+		if info.Tables == nil {
+			info.Tables = make([]string, 0)
 		}
+		for _, w := range stmt.Writes {
+			info.Writes = append(info.Writes, w.Object)
+		}
+		result.Statements = append(result.Statements, info)
+
+		s.analyzeStatement(stmt, index, add)
 	}
 
-	// 检查注入特征
-	for _, pattern := range injectionPatterns {
-		if matched, _ := regexp.MatchString(fmt.Sprintf("(?i)%s", pattern), sql); matched {
-			result.Risk = model.RiskHigh
-			result.Description = "疑似SQL注入攻击"
-			result.Suggestion = "请使用参数化查询，避免拼接SQL"
-			return result
-		}
+	// 按风险从高到低排列，同级保持发现顺序
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		return riskRank[result.Findings[i].Risk] > riskRank[result.Findings[j].Risk]
+	})
+	if len(result.Findings) > 0 {
+		top := result.Findings[0]
+		result.Risk = top.Risk
+		result.Description = top.Message
+		result.Suggestion = top.Suggestion
 	}
+	return result
+}
 
-	// 检查查询性能
-	if strings.Contains(sql, "SELECT") {
-		// 检查是否有 WHERE 条件
-		if !strings.Contains(sql, "WHERE") {
-			result.Risk = model.RiskMedium
-			result.Description = "全表扫描风险"
-			result.Suggestion = "建议添加 WHERE 条件限制查询范围"
-			return result
-		}
+// analyzeStatement 分析一条语句
+func (s *SQLSecurityService) analyzeStatement(stmt *sqlparser.Statement, index int,
+	add func(rule string, risk model.SQLRisk, index int, message, suggestion string)) {
+	switch stmt.Type {
+	case sqlparser.TypeDCL:
+		add("dcl", model.RiskHigh, index, fmt.Sprintf("包含权限操作: %s", stmt.Verb), "账号和权限变更请联系DBA处理")
+	case sqlparser.TypeDDL:
+		add("ddl", model.RiskHigh, index, fmt.Sprintf("包含结构变更: %s", stmt.Verb), "结构变更请走变更流程")
+	case sqlparser.TypeDML:
+		add("dml", model.RiskMedium, index, fmt.Sprintf("包含数据修改: %s", stmt.Verb), "请确认是否需要执行数据修改操作")
+	}
+	if adminVerbs[stmt.Verb] {
+		add("admin_command", model.RiskHigh, index, fmt.Sprintf("包含管理操作: %s", stmt.Verb), "实例管理操作请联系DBA处理")
+	}
 
-		// 检查是否使用 SELECT *
-		if strings.Contains(sql, "SELECT *") {
-			result.Risk = model.RiskMedium
-			result.Description = "使用 SELECT * 可能影响性能"
-			result.Suggestion = "建议明确指定需要的字段"
-			return result
+	for _, w := range stmt.Writes {
+		switch {
+		case w.Verb == "DELETE" && !w.Filtered:
+			add("delete_without_where", model.RiskHigh, index, fmt.Sprintf("DELETE 未指定 WHERE 条件，将删除表 %s 的全部数据", w.Object),
+				"请添加 WHERE 条件限制删除范围")
+		case w.Verb == "UPDATE" && !w.Filtered:
+			add("update_without_where", model.RiskHigh, index, fmt.Sprintf("UPDATE 未指定 WHERE 条件，将更新表 %s 的全部数据", w.Object),
+				"请添加 WHERE 条件限制更新范围")
+		case w.Verb == "TRUNCATE" || strings.HasPrefix(w.Verb, "DROP "):
+			add("drop", model.RiskHigh, index, fmt.Sprintf("%s 将删除 %s", w.Verb, w.Object), "请确认已备份数据")
+		case w.Verb == "REPLACE":
+			add("replace", model.RiskMedium, index, fmt.Sprintf("REPLACE 会删除表 %s 中主键或唯一键冲突的行", w.Object),
+				"如只需更新冲突行，请使用 INSERT ... ON DUPLICATE KEY UPDATE")
 		}
+	}
 
-		// 检查是否有排序
-		if strings.Contains(sql, "ORDER BY") {
-			result.Risk = model.RiskLow
-			result.Description = "包含排序操作"
-			result.Suggestion = "建议检查排序字段是否建立索引"
+	if stmt.Tautology {
+		if len(stmt.Writes) > 0 {
+			add("tautology", model.RiskHigh, index, "WHERE 条件恒为真，修改将作用于全部数据", "请检查 WHERE 条件")
+		} else {
+			add("tautology", model.RiskMedium, index, "WHERE 条件恒为真，疑似SQL注入", "请使用参数化查询，避免拼接SQL")
 		}
+	}
+	for _, fn := range stmt.Functions {
+		if isDangerousFunction(fn) {
+			add("dangerous_function", model.RiskHigh, index, fmt.Sprintf("包含危险函数: %s", fn), "请避免使用系统敏感函数")
+		}
+	}
+	if stmt.IntoFile {
+		add("into_outfile", model.RiskHigh, index, "查询结果写入服务器文件", "请通过导出功能下载查询结果")
+	}
 
-		// 检查是否有分组
-		if strings.Contains(sql, "GROUP BY") {
-			result.Risk = model.RiskLow
-			result.Description = "包含分组操作"
-			result.Suggestion = "建议检查分组字段是否建立索引"
+	for _, table := range stmt.Tables {
+		name := strings.ToLower(table)
+		schema, _, qualified := strings.Cut(name, ".")
+		switch {
+		case credentialTables[name]:
+			add("credential_table", model.RiskHigh, index, fmt.Sprintf("访问账号密码表: %s", table), "请勿查询账号密码信息")
+		case qualified && systemSchemas[schema]:
+			add("system_schema", model.RiskMedium, index, fmt.Sprintf("访问系统库: %s", table), "请确认是否需要查询系统信息")
 		}
 	}
 
-	return result
+	if stmt.Type != sqlparser.TypeRead || stmt.Verb != "SELECT" {
+		return
+	}
+	if stmt.Locking {
+		add("locking_read", model.RiskMedium, index, "加锁读会阻塞其他事务的修改", "只读查询请去掉 FOR UPDATE/LOCK IN SHARE MODE")
+	}
+	if len(stmt.Tables) > 0 && !stmt.HasWhere && !stmt.HasLimit {
+		add("select_full_scan", model.RiskMedium, index, "全表扫描风险", "建议添加 WHERE 条件或 LIMIT 限制查询范围")
+	}
+	if stmt.SelectAll {
+		add("select_star", model.RiskLow, index, "使用 SELECT * 可能影响性能", "建议明确指定需要的字段")
+	}
 }
 
-// ValidateSQL 验证SQL语句安全性
-func (s *SQLSecurityService) ValidateSQL(sql string) error {
-	result := s.AnalyzeSQL(sql)
-	if result.Risk == model.RiskHigh {
-		return fmt.Errorf("SQL安全风险: %s, %s", result.Description, result.Suggestion)
+// ValidateSQL 验证SQL语句安全性，存在高风险发现时返回错误
+func (s *SQLSecurityService) ValidateSQL(sql, dbType string) error {
+	result := s.AnalyzeSQL(sql, dbType)
+	if result.Risk != model.RiskHigh {
+		return nil
+	}
+	var messages []string
+	for _, f := range result.Findings {
+		if f.Risk == model.RiskHigh {
+			messages = append(messages, f.Message)
+		}
 	}
-	return nil
+	return fmt.Errorf("SQL安全风险: %s", strings.Join(messages, "; "))
 }
 
-// SanitizeSQL 清理SQL语句
-func (s *SQLSecurityService) SanitizeSQL(sql string) string {
-	// 移除注释
-	sql = regexp.MustCompile(`--.*$`).ReplaceAllString(sql, "")
-	sql = regexp.MustCompile(`/\*.*?\*/`).ReplaceAllString(sql, "")
-	sql = regexp.MustCompile(`#.*$`).ReplaceAllString(sql, "")
-
-	// 移除多余的空白字符
-	sql = regexp.MustCompile(`\s+`).ReplaceAllString(sql, " ")
-	
-	return strings.TrimSpace(sql)
+// SanitizeSQL 清理SQL语句：去掉注释并压缩空白，字符串常量内的内容保持不变
+func (s *SQLSecurityService) SanitizeSQL(sql, dbType string) string {
+	normalized, err := sqlparser.Normalize(sql, sqlDialect(dbType))
+	if err != nil {
+		// 无法解析时原样返回，由分析阶段报告错误
+		return strings.TrimSpace(sql)
+	}
+	return normalized
 }
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/log"
+	"tools-admin/backend/pkg/sqlparser"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrTicketStatusChanged 工单状态已被其他操作修改
+var ErrTicketStatusChanged = errors.New("工单状态已变更，请刷新后重试")
+
+// ticketBlockedRules 不允许通过工单执行的分析规则
+var ticketBlockedRules = map[string]bool{
+	"parse_error":        true,
+	"empty":              true,
+	"dcl":                true,
+	"admin_command":      true,
+	"dangerous_function": true,
+	"into_outfile":       true,
+	"credential_table":   true,
+}
+
+// newAudit 根据当前用户创建审计记录
+func newAudit(c *gin.Context, ticket *model.SQLTicket, action model.SQLAuditAction) *model.SQLAudit {
+	return &model.SQLAudit{
+		DatabaseID: ticket.DatabaseID,
+		UserID:     c.GetUint("user_id"),
+		Username:   c.GetString("username"),
+		SQL:        ticket.SQL,
+		Status:     "success",
+		ClientIP:   c.ClientIP(),
+		Action:     action,
+		TicketID:   ticket.ID,
+	}
+}
+
+// saveAudit 在事务中保存审计记录，审计记录保存失败时操作一并回滚
+func (s *DatabaseService) saveAudit(tx *gorm.DB, audit *model.SQLAudit) error {
+	if err := tx.Create(audit).Error; err != nil {
+		log.Error(fmt.Sprintf("保存SQL审计记录失败, 工单ID: %d, 操作: %s, 错误: %v", audit.TicketID, audit.Action, err))
+		return err
+	}
+	return nil
+}
+
+// approverRoles 返回数据库连接的审批角色，未指定时使用全局配置
+func approverRoles(dbConfig *model.Database) model.IDList {
+	if len(dbConfig.ApproverRoleIDs) > 0 {
+		return dbConfig.ApproverRoleIDs
+	}
+	return model.IDList(config.Config.SQLConsole.ApproverRoleIDs)
+}
+
+// SubmitTicket 提交SQL变更工单。只接受数据修改或单条结构变更，
+// 数据修改需声明预期影响行数
+func (s *DatabaseService) SubmitTicket(req *model.SQLTicketCreateReq, c *gin.Context) (*model.SQLTicket, error) {
+	var dbConfig model.Database
+	if err := s.db.First(&dbConfig, req.DatabaseID).Error; err != nil {
+		return nil, fmt.Errorf("database not found: %v", err)
+	}
+	roles := approverRoles(&dbConfig)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("数据库连接 %s 未配置审批角色", dbConfig.Name)
+	}
+
+	sql := s.sqlSecurity.SanitizeSQL(req.SQL, dbConfig.Type)
+	analysis := s.sqlSecurity.AnalyzeSQL(sql, dbConfig.Type)
+	for _, f := range analysis.Findings {
+		if ticketBlockedRules[f.Rule] {
+			return nil, fmt.Errorf("不允许通过工单执行: %s", f.Message)
+		}
+	}
+
+	stmtType, err := ticketStatementType(analysis.Statements)
+	if err != nil {
+		return nil, err
+	}
+	ticket := &model.SQLTicket{
+		DatabaseID:      dbConfig.ID,
+		SQL:             sql,
+		Reason:          req.Reason,
+		StatementType:   stmtType,
+		Risk:            analysis.Risk,
+		ApproverRoleIDs: roles,
+		Status:          model.TicketPending,
+		SubmitterID:     c.GetUint("user_id"),
+		SubmitterName:   c.GetString("username"),
+	}
+	if stmtType == string(sqlparser.TypeDML) {
+		if req.ExpectedRows == nil {
+			return nil, fmt.Errorf("数据修改需声明预期影响行数")
+		}
+		ticket.ExpectedRows = *req.ExpectedRows
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ticket).Error; err != nil {
+			return err
+		}
+		return s.saveAudit(tx, newAudit(c, ticket, model.AuditSubmit))
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("提交SQL变更工单失败: %v", err))
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("提交SQL变更工单, ID: %d, 数据库: %s, 提交人: %s", ticket.ID, dbConfig.Name, ticket.SubmitterName))
+	return ticket, nil
+}
+
+// ticketStatementType 检查工单语句并返回语句类型。
+// 结构变更在MySQL中会隐式提交事务，因此只能单独提交一条
+func ticketStatementType(statements []*model.SQLStatementInfo) (string, error) {
+	ddl, dml := 0, 0
+	for i, stmt := range statements {
+		switch stmt.Type {
+		case string(sqlparser.TypeDML):
+			dml++
+		case string(sqlparser.TypeDDL):
+			ddl++
+		default:
+			return "", fmt.Errorf("第%d条语句 %s 不是数据修改或结构变更，查询请使用SQL控制台", i+1, stmt.Verb)
+		}
+	}
+	switch {
+	case ddl > 0 && len(statements) > 1:
+		return "", fmt.Errorf("结构变更需单独提交，每个工单只能包含一条")
+	case ddl > 0:
+		return string(sqlparser.TypeDDL), nil
+	case dml > 0:
+		return string(sqlparser.TypeDML), nil
+	}
+	return "", fmt.Errorf("SQL语句为空")
+}
+
+// ListTickets 获取变更工单列表
+func (s *DatabaseService) ListTickets(req *model.SQLTicketListReq, c *gin.Context) (*model.SQLTicketListResp, error) {
+	query := s.db.Model(&model.SQLTicket{})
+	if req.DatabaseID != 0 {
+		query = query.Where("database_id = ?", req.DatabaseID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Mine {
+		query = query.Where("submitter_id = ?", c.GetUint("user_id"))
+	}
+	if req.Reviewable {
+		query = query.Where("status = ? AND submitter_id <> ? AND FIND_IN_SET(?, approver_role_ids) > 0",
+			model.TicketPending, c.GetUint("user_id"), c.GetUint("role_id"))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	resp := &model.SQLTicketListResp{Total: total, List: make([]*model.SQLTicket, 0)}
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&resp.List).Error; err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetTicket 获取变更工单详情
+func (s *DatabaseService) GetTicket(id uint) (*model.SQLTicketDetail, error) {
+	var ticket model.SQLTicket
+	if err := s.db.First(&ticket, id).Error; err != nil {
+		return nil, err
+	}
+	var dbConfig model.Database
+	// 数据库连接已删除时按默认方言分析
+	if err := s.db.First(&dbConfig, ticket.DatabaseID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	detail := &model.SQLTicketDetail{
+		SQLTicket: &ticket,
+		Analysis:  s.sqlSecurity.AnalyzeSQL(ticket.SQL, dbConfig.Type),
+		Audits:    make([]*model.SQLAudit, 0),
+	}
+	if err := s.db.Where("ticket_id = ?", ticket.ID).Order("id").Find(&detail.Audits).Error; err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// ReviewTicket 审批或驳回工单。审批人需为工单的审批角色，且不能审批自己提交的工单
+func (s *DatabaseService) ReviewTicket(id uint, approve bool, req *model.SQLTicketReviewReq, c *gin.Context) (*model.SQLTicket, error) {
+	var ticket model.SQLTicket
+	if err := s.db.First(&ticket, id).Error; err != nil {
+		return nil, err
+	}
+	if ticket.Status != model.TicketPending {
+		return nil, fmt.Errorf("工单状态为 %s，不能审批", ticket.Status)
+	}
+	userID := c.GetUint("user_id")
+	if !ticket.ApproverRoleIDs.Contains(c.GetUint("role_id")) {
+		return nil, fmt.Errorf("当前用户的角色无权审批该工单")
+	}
+	if ticket.SubmitterID == userID {
+		return nil, fmt.Errorf("不能审批自己提交的工单")
+	}
+
+	status, action := model.TicketRejected, model.AuditReject
+	if approve {
+		status, action = model.TicketApproved, model.AuditApprove
+	}
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ticket).Where("status = ?", model.TicketPending).Updates(map[string]interface{}{
+			"status":          status,
+			"approver_id":     userID,
+			"approver_name":   c.GetString("username"),
+			"approve_comment": req.Comment,
+			"approved_at":     now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTicketStatusChanged
+		}
+		return s.saveAudit(tx, newAudit(c, &ticket, action))
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("SQL变更工单审批完成, ID: %d, 结果: %s, 审批人: %s", ticket.ID, status, c.GetString("username")))
+	return s.reloadTicket(id)
+}
+
+// CancelTicket 撤销未执行的工单，只有提交人可以撤销
+func (s *DatabaseService) CancelTicket(id uint, c *gin.Context) (*model.SQLTicket, error) {
+	var ticket model.SQLTicket
+	if err := s.db.First(&ticket, id).Error; err != nil {
+		return nil, err
+	}
+	if ticket.SubmitterID != c.GetUint("user_id") {
+		return nil, fmt.Errorf("只有提交人可以撤销工单")
+	}
+	if ticket.Status != model.TicketPending && ticket.Status != model.TicketApproved {
+		return nil, fmt.Errorf("工单状态为 %s，不能撤销", ticket.Status)
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ticket).Where("status = ?", ticket.Status).Update("status", model.TicketCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTicketStatusChanged
+		}
+		return s.saveAudit(tx, newAudit(c, &ticket, model.AuditCancel))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.reloadTicket(id)
+}
+
+// ExecuteTicket 执行已审批的工单，提交人或审批角色可以执行。
+// 所有语句在一个事务中执行，数据修改的影响行数与预期不一致时回滚。
+// MySQL的结构变更会隐式提交，无法回滚
+func (s *DatabaseService) ExecuteTicket(id uint, c *gin.Context) (*model.SQLTicket, error) {
+	var ticket model.SQLTicket
+	if err := s.db.First(&ticket, id).Error; err != nil {
+		return nil, err
+	}
+	userID := c.GetUint("user_id")
+	if ticket.SubmitterID != userID && !ticket.ApproverRoleIDs.Contains(c.GetUint("role_id")) {
+		return nil, fmt.Errorf("只有提交人或审批角色可以执行工单")
+	}
+	if ticket.Status != model.TicketApproved {
+		return nil, fmt.Errorf("工单状态为 %s，不能执行", ticket.Status)
+	}
+
+	// 先将状态改为执行中，防止重复执行
+	result := s.db.Model(&ticket).Where("status = ?", model.TicketApproved).Updates(map[string]interface{}{
+		"status":        model.TicketExecuting,
+		"executor_id":   userID,
+		"executor_name": c.GetString("username"),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTicketStatusChanged
+	}
+
+	startTime := time.Now()
+	affected, execErr := s.runTicket(&ticket)
+	audit := newAudit(c, &ticket, model.AuditExecute)
+	audit.Duration = time.Since(startTime).Milliseconds()
+	audit.AffectedRows = affected
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":        model.TicketExecuted,
+		"affected_rows": affected,
+		"executed_at":   now,
+		"error":         "",
+	}
+	if execErr != nil {
+		updates["status"] = model.TicketFailed
+		updates["error"] = execErr.Error()
+		audit.Status = "failed"
+		audit.Error = execErr.Error()
+		log.Error(fmt.Sprintf("执行SQL变更工单失败, ID: %d, 错误: %v", ticket.ID, execErr))
+	} else {
+		log.Info(fmt.Sprintf("执行SQL变更工单成功, ID: %d, 影响行数: %d", ticket.ID, affected))
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ticket).Updates(updates).Error; err != nil {
+			return err
+		}
+		return s.saveAudit(tx, audit)
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("更新SQL变更工单状态失败, ID: %d, 错误: %v", ticket.ID, err))
+		return nil, err
+	}
+	return s.reloadTicket(id)
+}
+
+// runTicket 在事务中执行工单的语句，返回影响行数合计
+func (s *DatabaseService) runTicket(ticket *model.SQLTicket) (int64, error) {
+	var dbConfig model.Database
+	if err := s.db.First(&dbConfig, ticket.DatabaseID).Error; err != nil {
+		return 0, fmt.Errorf("database not found: %v", err)
+	}
+	statements, err := s.sqlSecurity.ParseSQL(ticket.SQL, dbConfig.Type)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := s.getConnection(ticket.DatabaseID)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %v", err)
+	}
+	var affected int64
+	for i, stmt := range statements {
+		res, err := tx.ExecContext(ctx, stmt.Text)
+		if err != nil {
+			tx.Rollback()
+			return affected, fmt.Errorf("第%d条语句执行失败，已回滚: %v", i+1, err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			affected += n
+		}
+	}
+	if ticket.StatementType == string(sqlparser.TypeDML) && affected != ticket.ExpectedRows {
+		tx.Rollback()
+		return affected, fmt.Errorf("影响行数 %d 与预期 %d 不一致，已回滚", affected, ticket.ExpectedRows)
+	}
+	if err := tx.Commit(); err != nil {
+		return affected, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return affected, nil
+}
+
+func (s *DatabaseService) reloadTicket(id uint) (*model.SQLTicket, error) {
+	var ticket model.SQLTicket
+	if err := s.db.First(&ticket, id).Error; err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
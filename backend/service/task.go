@@ -1,15 +1,31 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"tools-admin/backend/model"
 	"tools-admin/backend/pkg/cronutil"
 	"tools-admin/backend/pkg/db"
 	"tools-admin/backend/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxRetryAttempts    = 10
+	defaultMisfireLimit = 10
+	maxMisfireLimit     = 100
 )
 
+// ErrInvalidTask 任务配置校验失败
+var ErrInvalidTask = errors.New("任务内容或参数无效")
+
+// taskKeyPattern 任务标识格式
+var taskKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
 type TaskService struct{}
 
 // List 获取任务列表
@@ -18,15 +34,7 @@ func (s *TaskService) List(page, pageSize int, name string, taskType int) ([]*mo
 	var total int64
 
 	// 构建查询
-	dbQuery := db.Db.Model(&model.Task{})
-
-	// 添加查询条件
-	if name != "" {
-		dbQuery = dbQuery.Where("name LIKE ?", "%"+name+"%")
-	}
-	if taskType != 0 {
-		dbQuery = dbQuery.Where("type = ?", taskType)
-	}
+	dbQuery := s.filter(name, taskType)
 
 	// 获取总数
 	if err := dbQuery.Count(&total).Error; err != nil {
@@ -50,6 +58,18 @@ func (s *TaskService) List(page, pageSize int, name string, taskType int) ([]*mo
 	return responses, total, nil
 }
 
+// filter 按名称和类型构建任务查询，条件为空时不过滤
+func (s *TaskService) filter(name string, taskType int) *gorm.DB {
+	dbQuery := db.Db.Model(&model.Task{})
+	if name != "" {
+		dbQuery = dbQuery.Where("name LIKE ?", "%"+name+"%")
+	}
+	if taskType != 0 {
+		dbQuery = dbQuery.Where("type = ?", taskType)
+	}
+	return dbQuery
+}
+
 // Get 获取单个任务
 func (s *TaskService) Get(id uint) (*model.TaskResponse, error) {
 	var task model.Task
@@ -70,8 +90,130 @@ func (s *TaskService) GetByID(id uint) (*model.Task, error) {
 	return &task, nil
 }
 
-// Create 创建任务
-func (s *TaskService) Create(task *model.Task) error {
+// Validate 校验重试策略、任务依赖、模板语法，并使用任务类型对应的执行器校验任务内容和参数，
+// 没有执行器的任务类型不做内容校验
+func (s *TaskService) Validate(task *model.Task) error {
+	if err := s.validateKey(task); err != nil {
+		return err
+	}
+	if err := cronutil.ValidateTimezone(task.Timezone); err != nil {
+		return err
+	}
+	if task.CalendarID != 0 {
+		if err := db.Db.Select("id").First(&model.Calendar{}, task.CalendarID).Error; err != nil {
+			return fmt.Errorf("日历不存在, ID: %d", task.CalendarID)
+		}
+	}
+	if err := s.validateRetry(task); err != nil {
+		return err
+	}
+	if err := s.validateConcurrency(task); err != nil {
+		return err
+	}
+	if err := s.validateMisfire(task); err != nil {
+		return err
+	}
+	if task.LogRetentionDays < 0 || task.LogRetentionRuns < 0 {
+		return fmt.Errorf("日志保留天数和批次数不能为负数")
+	}
+	if err := s.validateDependencies(task); err != nil {
+		return err
+	}
+	if err := validateTemplate(task); err != nil {
+		return err
+	}
+
+	executor, ok := GetExecutor(task.Type)
+	if !ok {
+		return nil
+	}
+	return executor.Validate(task)
+}
+
+// validateKey 校验任务标识的格式和唯一性，为空时创建后自动生成；并发创建相同标识时由唯一索引拒绝
+func (s *TaskService) validateKey(task *model.Task) error {
+	if task.Key == "" {
+		return nil
+	}
+	if !taskKeyPattern.MatchString(task.Key) {
+		return fmt.Errorf("无效的任务标识: %s，只能包含字母、数字、下划线、中划线和点，且不超过100个字符", task.Key)
+	}
+	var count int64
+	if err := db.Db.Model(&model.Task{}).Where("task_key = ? AND id <> ?", task.Key, task.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("任务标识已存在: %s", task.Key)
+	}
+	return nil
+}
+
+// validateRetry 校验并补全重试策略
+func (s *TaskService) validateRetry(task *model.Task) error {
+	if task.RetryMaxAttempts <= 0 {
+		task.RetryMaxAttempts = 1
+	}
+	if task.RetryMaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("最大执行次数不能超过%d", maxRetryAttempts)
+	}
+	if task.RetryBackoff == "" {
+		task.RetryBackoff = model.RetryBackoffFixed
+	}
+	if task.RetryBackoff != model.RetryBackoffFixed && task.RetryBackoff != model.RetryBackoffExponential {
+		return fmt.Errorf("无效的退避策略: %s", task.RetryBackoff)
+	}
+	if task.RetryInterval < 0 {
+		return fmt.Errorf("重试间隔不能为负数")
+	}
+	for _, kind := range task.RetryOnKinds() {
+		if !model.RetryableFailureKinds[kind] {
+			return fmt.Errorf("不支持重试的失败类型: %s", kind)
+		}
+	}
+	return nil
+}
+
+// validateConcurrency 校验并补全优先级和并发策略
+func (s *TaskService) validateConcurrency(task *model.Task) error {
+	if task.Priority == "" {
+		task.Priority = model.TaskPriorityMedium
+	}
+	if _, ok := model.TaskPriorityRank[task.Priority]; !ok {
+		return fmt.Errorf("无效的优先级: %s", task.Priority)
+	}
+	if task.ConcurrencyPolicy == "" {
+		task.ConcurrencyPolicy = model.ConcurrencyAllow
+	}
+	if _, ok := model.ConcurrencyPolicyMap[task.ConcurrencyPolicy]; !ok {
+		return fmt.Errorf("无效的并发策略: %s", task.ConcurrencyPolicy)
+	}
+	return nil
+}
+
+// validateMisfire 校验并补全错过调度的处理策略
+func (s *TaskService) validateMisfire(task *model.Task) error {
+	if task.MisfirePolicy == "" {
+		task.MisfirePolicy = model.MisfireSkip
+	}
+	if _, ok := model.MisfirePolicyMap[task.MisfirePolicy]; !ok {
+		return fmt.Errorf("无效的错过调度处理策略: %s", task.MisfirePolicy)
+	}
+	if task.MisfireLimit <= 0 {
+		task.MisfireLimit = defaultMisfireLimit
+	}
+	if task.MisfireLimit > maxMisfireLimit {
+		return fmt.Errorf("补执行次数不能超过%d", maxMisfireLimit)
+	}
+	return nil
+}
+
+// Create 创建任务，并记录为版本1
+func (s *TaskService) Create(task *model.Task, operator model.Operator) error {
+	if err := s.Validate(task); err != nil {
+		log.Error(fmt.Sprintf("任务校验失败: %v", err))
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+
 	// 验证cron表达式
 	if task.CronExpr != "" {
 		if err := cronutil.ValidateCronExpr(task.CronExpr); err != nil {
@@ -80,7 +222,7 @@ func (s *TaskService) Create(task *model.Task) error {
 		}
 
 		// 计算下次执行时间
-		nextRunTime, err := cronutil.GetNextRunTime(task.CronExpr)
+		nextRunTime, err := taskNextRunTime(task, time.Now())
 		if err != nil {
 			log.Error(fmt.Sprintf("计算下次执行时间失败: %v", err))
 			return err
@@ -88,18 +230,53 @@ func (s *TaskService) Create(task *model.Task) error {
 		task.NextRunTime = nextRunTime
 	}
 
-	if err := db.Db.Create(task).Error; err != nil {
+	task.Revision = 1
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		if task.Key == "" {
+			task.Key = fmt.Sprintf("task-%d", task.ID)
+			if err := tx.Model(task).Update("task_key", task.Key).Error; err != nil {
+				return err
+			}
+		}
+		revision, err := model.NewTaskRevision(task, model.RevisionActionCreate, operator)
+		if err != nil {
+			return err
+		}
+		return tx.Create(revision).Error
+	})
+	if err != nil {
 		log.Error(fmt.Sprintf("创建任务失败: %v", err))
 		return err
 	}
+
+	if err := TaskScheduler.Schedule(task); err != nil {
+		log.Error(fmt.Sprintf("注册任务调度失败, ID: %d, 错误: %v", task.ID, err))
+	}
 	return nil
 }
 
-// Update 更新任务
-func (s *TaskService) Update(task *model.Task) error {
+// Update 更新任务，配置有变化时记录新版本
+func (s *TaskService) Update(task *model.Task, operator model.Operator) error {
+	return s.save(task, model.RevisionActionUpdate, 0, operator)
+}
+
+// save 校验并保存任务，在同一事务中记录版本，然后更新调度
+func (s *TaskService) save(task *model.Task, action string, rollbackFrom int, operator model.Operator) error {
+	// 标识为空时补全，避免保存空字符串与其他任务的唯一索引冲突
+	if task.Key == "" {
+		task.Key = fmt.Sprintf("task-%d", task.ID)
+	}
+	if err := s.Validate(task); err != nil {
+		log.Error(fmt.Sprintf("任务校验失败: %v", err))
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+
 	// 如果有cron表达式，重新计算下次执行时间
 	if task.CronExpr != "" {
-		nextRunTime, err := cronutil.GetNextRunTime(task.CronExpr)
+		nextRunTime, err := taskNextRunTime(task, time.Now())
 		if err != nil {
 			log.Error(fmt.Sprintf("计算下次执行时间失败: %v", err))
 			return err
@@ -107,37 +284,91 @@ func (s *TaskService) Update(task *model.Task) error {
 		task.NextRunTime = nextRunTime
 	}
 
-	if err := db.Db.Save(task).Error; err != nil {
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := s.recordRevision(tx, task, action, rollbackFrom, operator); err != nil {
+			return err
+		}
+		return tx.Save(task).Error
+	})
+	if err != nil {
 		log.Error(fmt.Sprintf("更新任务失败: %v", err))
 		return err
 	}
+
+	if err := TaskScheduler.Schedule(task); err != nil {
+		log.Error(fmt.Sprintf("更新任务调度失败, ID: %d, 错误: %v", task.ID, err))
+	}
 	return nil
 }
 
 // Delete 删除任务
 func (s *TaskService) Delete(id uint) error {
-	if err := db.Db.Delete(&model.Task{}, id).Error; err != nil {
+	if err := deleteTasks([]uint{id}); err != nil {
 		log.Error(fmt.Sprintf("删除任务失败, ID: %d, 错误: %v", id, err))
 		return err
 	}
+
+	TaskScheduler.Remove(id)
+	s.removeUpstreamReferences([]uint{id})
 	return nil
 }
 
+// deleteTasks 删除任务并置空任务标识，使标识可以被新任务使用
+func deleteTasks(ids []uint) error {
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Task{}).Where("id IN ?", ids).Update("task_key", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Task{}, ids).Error
+	})
+}
+
 // BatchDelete 批量删除任务
 func (s *TaskService) BatchDelete(ids []uint) error {
-	if err := db.Db.Delete(&model.Task{}, ids).Error; err != nil {
+	if err := deleteTasks(ids); err != nil {
 		log.Error(fmt.Sprintf("批量删除任务失败, IDs: %v, 错误: %v", ids, err))
 		return err
 	}
+
+	for _, id := range ids {
+		TaskScheduler.Remove(id)
+	}
+	s.removeUpstreamReferences(ids)
 	return nil
 }
 
-// GetLogs 获取任务日志
-func (s *TaskService) GetLogs(taskID uint) ([]*model.TaskLogResponse, error) {
+// GetLogs 分页获取任务日志，按执行状态、批次、开始时间和关键字过滤
+func (s *TaskService) GetLogs(taskID uint, query *model.TaskLogQuery) ([]*model.TaskLogResponse, int64, error) {
 	var logs []*model.TaskLog
-	if err := db.Db.Where("task_id = ?", taskID).Order("id desc").Find(&logs).Error; err != nil {
+	var total int64
+
+	dbQuery := db.Db.Model(&model.TaskLog{}).Where("task_id = ?", taskID)
+	if query.Status != 0 {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+	if query.RunID != "" {
+		dbQuery = dbQuery.Where("run_id = ?", query.RunID)
+	}
+	if query.StartFrom != nil {
+		dbQuery = dbQuery.Where("start_time >= ?", *query.StartFrom)
+	}
+	if query.StartTo != nil {
+		dbQuery = dbQuery.Where("start_time < ?", *query.StartTo)
+	}
+	if query.Keyword != "" {
+		keyword := "%" + query.Keyword + "%"
+		dbQuery = dbQuery.Where("output LIKE ? OR error LIKE ?", keyword, keyword)
+	}
+
+	if err := dbQuery.Count(&total).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务日志总数失败, TaskID: %d, 错误: %v", taskID, err))
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := dbQuery.Offset(offset).Limit(query.PageSize).Order("id desc").Find(&logs).Error; err != nil {
 		log.Error(fmt.Sprintf("获取任务日志失败, TaskID: %d, 错误: %v", taskID, err))
-		return nil, err
+		return nil, 0, err
 	}
 
 	// 转换为响应格式
@@ -145,31 +376,29 @@ func (s *TaskService) GetLogs(taskID uint) ([]*model.TaskLogResponse, error) {
 	for _, log := range logs {
 		responses = append(responses, log.ToResponse())
 	}
-	return responses, nil
+	return responses, total, nil
 }
 
-// RunTask 运行任务
-func (s *TaskService) RunTask(id uint) error {
+// RunTask 运行任务，返回执行批次ID。params 覆盖任务参数中声明的默认参数，
+// 传入 scheduled_time 可按指定的计划时间渲染日期变量，用于补数据
+func (s *TaskService) RunTask(id uint, params map[string]interface{}) (string, error) {
 	// 获取任务
 	task, err := s.GetByID(id)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 检查任务状态
 	if task.Status != model.TaskStatusStarted {
-		return fmt.Errorf("任务未启动，无法执行")
+		return "", fmt.Errorf("任务未启动，无法执行")
 	}
 
-	// 更新执行状态为执行中
-	task.ExecStatus = model.TaskExecStatusRunning
-	if err := s.Update(task); err != nil {
-		return err
+	// 提交到执行池异步执行，避免长时间任务阻塞请求
+	run, err := s.dispatch(task, time.Now(), params)
+	if err != nil {
+		return "", err
 	}
-
-	// TODO: 实际执行任务的逻辑
-
-	return nil
+	return run.ID, nil
 }
 
 // UpdateTaskStatus 更新任务状态
@@ -185,6 +414,9 @@ func (s *TaskService) UpdateTaskStatus(taskID int64, status model.TaskStatus) er
 		return fmt.Errorf("更新任务状态失败")
 	}
 
+	if err := TaskScheduler.Reschedule(uint(taskID)); err != nil {
+		log.Error(fmt.Sprintf("更新任务调度失败, ID: %d, 错误: %v", taskID, err))
+	}
 	return nil
 }
 
@@ -201,29 +433,43 @@ func (s *TaskService) BatchUpdateTaskStatus(ids []uint, status model.TaskStatus)
 		return fmt.Errorf("批量更新任务状态失败")
 	}
 
+	for _, id := range ids {
+		if err := TaskScheduler.Reschedule(id); err != nil {
+			log.Error(fmt.Sprintf("更新任务调度失败, ID: %d, 错误: %v", id, err))
+		}
+	}
 	return nil
 }
 
-// GetNextRunTimes 根据cron表达式获取未来4次执行时间
-func (s *TaskService) GetNextRunTimes(cronExpr string) ([]string, error) {
-	if err := cronutil.ValidateCronExpr(cronExpr); err != nil {
+// GetNextRunTimes 根据cron表达式获取未来4次执行时间，按指定时区显示；
+// 每个时间的UTC偏移单独返回，夏令时重复区间内的时间据此区分；指定日历时跳过日历排除的时间
+func (s *TaskService) GetNextRunTimes(cronExpr, timezone string, calendarID uint) (*model.NextRunTimes, error) {
+	loc, err := cronutil.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := buildSchedule(cronExpr, timezone, calendarID)
+	if err != nil {
 		return nil, err
 	}
 
-	times := make([]string, 0, 4)
+	result := &model.NextRunTimes{
+		Timezone: loc.String(),
+		Times:    make([]string, 0, 4),
+		Offsets:  make([]string, 0, 4),
+	}
 	current := time.Now()
 
 	for i := 0; i < 4; i++ {
-		nextTime, err := cronutil.GetNextRunTimeFrom(cronExpr, current)
-		if err != nil {
-			return nil, err
-		}
-		if nextTime == nil {
+		nextTime := schedule.Next(current)
+		if nextTime.IsZero() {
 			break
 		}
-		times = append(times, nextTime.Format("2006-01-02 15:04:05"))
-		current = *nextTime
+		local := nextTime.In(loc)
+		result.Times = append(result.Times, local.Format("2006-01-02 15:04:05"))
+		result.Offsets = append(result.Offsets, local.Format("-07:00"))
+		current = nextTime
 	}
 
-	return times, nil
+	return result, nil
 }
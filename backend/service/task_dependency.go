@@ -0,0 +1,270 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+)
+
+// validateDependencies 校验上游任务及触发规则，并检查依赖是否成环
+func (s *TaskService) validateDependencies(task *model.Task) error {
+	if task.TriggerRule == "" {
+		task.TriggerRule = model.TriggerRuleAllSuccess
+	}
+	if _, ok := model.TriggerRuleMap[task.TriggerRule]; !ok {
+		return fmt.Errorf("无效的触发规则: %s", task.TriggerRule)
+	}
+	if len(task.UpstreamIDs) == 0 {
+		return nil
+	}
+
+	// 加载所有任务的依赖关系
+	var tasks []*model.Task
+	if err := db.Db.Select("id", "name", "upstream_ids").Find(&tasks).Error; err != nil {
+		return fmt.Errorf("加载任务依赖失败: %v", err)
+	}
+	upstreams := make(map[uint]model.IDList, len(tasks))
+	names := make(map[uint]string, len(tasks))
+	for _, t := range tasks {
+		upstreams[t.ID] = t.UpstreamIDs
+		names[t.ID] = t.Name
+	}
+
+	seen := make(map[uint]bool)
+	for _, id := range task.UpstreamIDs {
+		if task.ID != 0 && id == task.ID {
+			return fmt.Errorf("任务不能依赖自身")
+		}
+		if _, ok := upstreams[id]; !ok {
+			return fmt.Errorf("上游任务不存在, ID: %d", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("上游任务重复, ID: %d", id)
+		}
+		seen[id] = true
+	}
+
+	// 新建任务不会被其他任务依赖，不可能成环
+	if task.ID == 0 {
+		return nil
+	}
+	upstreams[task.ID] = task.UpstreamIDs
+	if cycle := findDependencyCycle(upstreams, task.ID); cycle != nil {
+		path := make([]string, 0, len(cycle))
+		for _, id := range cycle {
+			path = append(path, fmt.Sprintf("%s(%d)", names[id], id))
+		}
+		return fmt.Errorf("任务依赖存在环: %s", strings.Join(path, " -> "))
+	}
+	return nil
+}
+
+// findDependencyCycle 沿上游方向查找经过start的环，返回环上的任务ID，不存在时返回nil
+func findDependencyCycle(upstreams map[uint]model.IDList, start uint) []uint {
+	visited := make(map[uint]bool)
+	var path []uint
+
+	var dfs func(id uint) bool
+	dfs = func(id uint) bool {
+		path = append(path, id)
+		for _, up := range upstreams[id] {
+			if up == start {
+				path = append(path, up)
+				return true
+			}
+			if !visited[up] {
+				visited[up] = true
+				if dfs(up) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if dfs(start) {
+		return path
+	}
+	return nil
+}
+
+// removeUpstreamReferences 任务删除后，将其从其他任务的上游列表中移除
+func (s *TaskService) removeUpstreamReferences(ids []uint) {
+	var tasks []*model.Task
+	if err := db.Db.Select("id", "upstream_ids").Where("upstream_ids <> ''").Find(&tasks).Error; err != nil {
+		log.Error(fmt.Sprintf("加载任务依赖失败: %v", err))
+		return
+	}
+
+	removed := model.IDList(ids)
+	for _, t := range tasks {
+		kept := model.IDList{}
+		for _, id := range t.UpstreamIDs {
+			if !removed.Contains(id) {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(t.UpstreamIDs) {
+			continue
+		}
+		if err := db.Db.Model(&model.Task{}).Where("id = ?", t.ID).Update("upstream_ids", kept).Error; err != nil {
+			log.Error(fmt.Sprintf("更新任务依赖失败, ID: %d, 错误: %v", t.ID, err))
+		}
+	}
+}
+
+// GetDependencyGraph 获取任务依赖图，taskID为0时返回所有存在依赖关系的任务，
+// 否则返回该任务所在的连通子图
+func (s *TaskService) GetDependencyGraph(taskID uint) (*model.TaskGraph, error) {
+	var tasks []*model.Task
+	if err := db.Db.Find(&tasks).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务列表失败: %v", err))
+		return nil, err
+	}
+
+	byID := make(map[uint]*model.Task, len(tasks))
+	neighbors := make(map[uint][]uint)
+	var edges []*model.TaskGraphEdge
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	for _, t := range tasks {
+		for _, up := range t.UpstreamIDs {
+			if _, ok := byID[up]; !ok {
+				continue
+			}
+			edges = append(edges, &model.TaskGraphEdge{From: up, To: t.ID})
+			neighbors[up] = append(neighbors[up], t.ID)
+			neighbors[t.ID] = append(neighbors[t.ID], up)
+		}
+	}
+
+	// 确定需要返回的节点
+	included := make(map[uint]bool)
+	if taskID == 0 {
+		for id := range neighbors {
+			included[id] = true
+		}
+	} else {
+		if _, ok := byID[taskID]; !ok {
+			return nil, fmt.Errorf("任务不存在, ID: %d", taskID)
+		}
+		queue := []uint{taskID}
+		included[taskID] = true
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, next := range neighbors[id] {
+				if !included[next] {
+					included[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	graph := &model.TaskGraph{
+		Nodes: make([]*model.TaskGraphNode, 0, len(included)),
+		Edges: make([]*model.TaskGraphEdge, 0),
+	}
+	for _, t := range tasks {
+		if !included[t.ID] {
+			continue
+		}
+		graph.Nodes = append(graph.Nodes, &model.TaskGraphNode{
+			ID:          t.ID,
+			Name:        t.Name,
+			Type:        int8(t.Type),
+			Status:      int8(t.Status),
+			ExecStatus:  int8(t.ExecStatus),
+			CronExpr:    t.CronExpr,
+			TriggerRule: t.TriggerRule,
+		})
+	}
+	for _, e := range edges {
+		if included[e.From] && included[e.To] {
+			graph.Edges = append(graph.Edges, e)
+		}
+	}
+	return graph, nil
+}
+
+// triggerDownstream 上游任务的一次执行结束后，检查并触发满足规则的下游任务
+func (s *Scheduler) triggerDownstream(upstreamID uint) {
+	s.depMu.Lock()
+	defer s.depMu.Unlock()
+
+	var candidates []*model.Task
+	if err := db.Db.Where("status = ? AND upstream_ids <> ''", model.TaskStatusStarted).Find(&candidates).Error; err != nil {
+		log.Error(fmt.Sprintf("加载下游任务失败: %v", err))
+		return
+	}
+
+	for _, task := range candidates {
+		if !task.UpstreamIDs.Contains(upstreamID) {
+			continue
+		}
+
+		ready, err := s.dependenciesMet(task)
+		if err != nil {
+			log.Error(fmt.Sprintf("检查任务依赖失败, ID: %d, 错误: %v", task.ID, err))
+			continue
+		}
+		if !ready {
+			continue
+		}
+
+		// 先记录本次触发时间，避免后续上游结束时重复触发
+		now := time.Now()
+		task.LastRunTime = &now
+		if err := db.Db.Model(&model.Task{}).Where("id = ?", task.ID).Update("last_run_time", now).Error; err != nil {
+			log.Error(fmt.Sprintf("更新任务执行时间失败, ID: %d, 错误: %v", task.ID, err))
+			continue
+		}
+		log.Info(fmt.Sprintf("上游任务 %d 执行结束，触发下游任务 %d（规则: %s）", upstreamID, task.ID, task.TriggerRule))
+		if _, err := s.taskService.dispatch(task, now, nil); err != nil {
+			log.Info(fmt.Sprintf("下游任务本次未执行, ID: %d, 原因: %v", task.ID, err))
+		}
+	}
+}
+
+// dependenciesMet 判断下游任务自上次执行以来，上游执行结果是否满足触发规则
+func (s *Scheduler) dependenciesMet(task *model.Task) (bool, error) {
+	since := task.CreatedAt
+	if task.LastRunTime != nil {
+		since = *task.LastRunTime
+	}
+
+	finished, succeeded := 0, 0
+	for _, upstreamID := range task.UpstreamIDs {
+		var latest model.TaskLog
+		result := db.Db.Where("task_id = ? AND retried = ? AND status <> ? AND end_time > ?",
+			upstreamID, false, model.TaskExecStatusSkipped, since).
+			Order("end_time desc").Limit(1).Find(&latest)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		finished++
+		if latest.Status == model.TaskExecStatusSuccess {
+			succeeded++
+		}
+	}
+
+	total := len(task.UpstreamIDs)
+	switch task.TriggerRule {
+	case model.TriggerRuleAnyDone:
+		return finished > 0, nil
+	case model.TriggerRuleAllDone:
+		return finished == total, nil
+	default:
+		return succeeded == total, nil
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLogPurgeInterval = time.Hour
+	defaultLogArchiveDir    = "./archive/task_logs"
+	defaultLogPurgeBatch    = 1000
+)
+
+// ErrArchiveNotFound 归档文件不存在
+var ErrArchiveNotFound = errors.New("归档文件不存在")
+
+// LogRetention 全局任务日志清理
+var LogRetention = newLogRetention()
+
+// logRetention 按保留策略定期清理任务日志，清理前先将日志压缩归档到文件。
+// 多副本部署时只有调度主节点执行定时清理
+type logRetention struct {
+	mu        sync.Mutex // 串行执行清理，避免定时清理与手动清理重复归档
+	interval  time.Duration
+	dir       string
+	batchSize int
+	global    model.TaskLogRetention
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+func newLogRetention() *logRetention {
+	cfg := config.Config.TaskLog
+	r := &logRetention{
+		interval:  defaultLogPurgeInterval,
+		dir:       defaultLogArchiveDir,
+		batchSize: defaultLogPurgeBatch,
+		global:    model.TaskLogRetention{Days: cfg.RetentionDays, Runs: cfg.RetentionRuns},
+		stopCh:    make(chan struct{}),
+	}
+	if cfg.PurgeInterval > 0 {
+		r.interval = time.Duration(cfg.PurgeInterval) * time.Minute
+	}
+	if cfg.ArchiveDir != "" {
+		r.dir = cfg.ArchiveDir
+	}
+	if cfg.BatchSize > 0 {
+		r.batchSize = cfg.BatchSize
+	}
+	return r
+}
+
+// Start 启动定时清理。归档由调度主节点写入，主节点会在副本间切换，
+// 开启主节点选举时归档目录必须是共享存储，否则拒绝启动
+func (r *logRetention) Start() error {
+	if config.Config.Scheduler.LeaderElection && !config.Config.TaskLog.ArchiveShared {
+		return fmt.Errorf("已开启调度主节点选举，任务日志归档目录 %s 必须为各副本共享的存储，确认后请设置 task_log.archive_shared", r.dir)
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("创建归档目录失败: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.purgeAll()
+			case <-r.stopCh:
+				return
+			}
+		}
+	}()
+	log.Info(fmt.Sprintf("任务日志清理已启动, 间隔: %s, 归档目录: %s", r.interval, r.dir))
+	return nil
+}
+
+// Stop 停止定时清理
+func (r *logRetention) Stop() {
+	r.stopOnce.Do(func() { close(r.stopCh) })
+}
+
+// policy 返回任务生效的保留策略，任务未设置的项使用全局配置
+func (r *logRetention) policy(task *model.Task) model.TaskLogRetention {
+	policy := r.global
+	if task.LogRetentionDays > 0 {
+		policy.Days = task.LogRetentionDays
+	}
+	if task.LogRetentionRuns > 0 {
+		policy.Runs = task.LogRetentionRuns
+	}
+	return policy
+}
+
+// purgeAll 按保留策略清理所有任务的日志，已删除任务的日志使用其删除前的策略
+func (r *logRetention) purgeAll() {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Error(fmt.Sprintf("任务日志清理异常: %v", rec))
+		}
+	}()
+	if !TaskScheduler.elector.isLeader() {
+		return
+	}
+
+	var tasks []*model.Task
+	if err := db.Db.Unscoped().Select("id", "log_retention_days", "log_retention_runs").Find(&tasks).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务日志保留策略失败: %v", err))
+		return
+	}
+	for _, task := range tasks {
+		result, err := r.Purge(task)
+		if err != nil {
+			log.Error(fmt.Sprintf("清理任务日志失败, TaskID: %d, 错误: %v", task.ID, err))
+			continue
+		}
+		if result.Purged > 0 {
+			log.Info(fmt.Sprintf("清理任务日志, TaskID: %d, 条数: %d, 归档文件: %d", task.ID, result.Purged, result.Archives))
+		}
+	}
+}
+
+// Purge 按保留策略清理任务的日志。日志按ID分批写入归档文件，每批归档成功后才删除，
+// 超出保留天数或保留批次数任一限制的日志都会被清理
+func (r *logRetention) Purge(task *model.Task) (*model.TaskLogPurgeResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &model.TaskLogPurgeResult{TaskID: task.ID}
+	policy := r.policy(task)
+	if !policy.Enabled() {
+		return result, nil
+	}
+
+	cond, args, err := r.expiredCondition(task.ID, policy)
+	if err != nil {
+		return nil, err
+	}
+	if cond == "" {
+		return result, nil
+	}
+	query := db.Db.Where("task_id = ?", task.ID).Where(cond, args...)
+
+	var lastID uint
+	for {
+		var logs []*model.TaskLog
+		if err := query.Session(&gorm.Session{}).Where("id > ?", lastID).Order("id").Limit(r.batchSize).Find(&logs).Error; err != nil {
+			return result, err
+		}
+		if len(logs) == 0 {
+			return result, nil
+		}
+		if err := r.archive(task.ID, logs); err != nil {
+			return result, err
+		}
+		ids := make([]uint, len(logs))
+		for i, l := range logs {
+			ids[i] = l.ID
+		}
+		if err := db.Db.Unscoped().Delete(&model.TaskLog{}, ids).Error; err != nil {
+			return result, err
+		}
+		result.Purged += len(logs)
+		result.Archives++
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// expiredCondition 返回超出保留策略的日志的查询条件，没有需要清理的日志时返回空条件
+func (r *logRetention) expiredCondition(taskID uint, policy model.TaskLogRetention) (string, []interface{}, error) {
+	var cond string
+	var args []interface{}
+	if policy.Days > 0 {
+		cond = "start_time < ?"
+		args = append(args, time.Now().AddDate(0, 0, -policy.Days))
+	}
+	if policy.Runs > 0 {
+		// 找出第N个最近批次的首条日志ID，更早的日志都属于更早的批次。
+		// 没有批次ID的历史日志每条单独作为一个批次
+		var firstIDs []uint
+		if err := db.Db.Model(&model.TaskLog{}).
+			Select("MIN(id) AS first_id").
+			Where("task_id = ?", taskID).
+			Group("COALESCE(NULLIF(run_id, ''), CONCAT('log-', id))").
+			Order("first_id DESC").
+			Offset(policy.Runs-1).Limit(1).
+			Pluck("first_id", &firstIDs).Error; err != nil {
+			return "", nil, err
+		}
+		if len(firstIDs) > 0 {
+			if cond != "" {
+				cond += " OR "
+			}
+			cond += "id < ?"
+			args = append(args, firstIDs[0])
+		}
+	}
+	if cond == "" {
+		return "", nil, nil
+	}
+	return "(" + cond + ")", args, nil
+}
+
+// archive 将一批日志写入gzip压缩的JSON Lines文件并记录归档。先写临时文件再改名，
+// 避免清理中断时留下不完整的归档
+func (r *logRetention) archive(taskID uint, logs []*model.TaskLog) error {
+	first, last := logs[0], logs[len(logs)-1]
+	name := filepath.Join(fmt.Sprintf("task_%d", taskID),
+		fmt.Sprintf("task_%d_%d-%d_%s.jsonl.gz", taskID, first.ID, last.ID, time.Now().Format("20060102150405")))
+	path := filepath.Join(r.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	archive := &model.TaskLogArchive{
+		TaskID:    taskID,
+		FileName:  filepath.ToSlash(name),
+		Node:      TaskScheduler.elector.owner,
+		Count:     len(logs),
+		MinLogID:  first.ID,
+		MaxLogID:  last.ID,
+		StartFrom: first.StartTime,
+		StartTo:   first.StartTime,
+	}
+	size, err := writeArchive(path, logs)
+	if err != nil {
+		return fmt.Errorf("写入归档文件失败: %v", err)
+	}
+	archive.Size = size
+	for _, l := range logs {
+		if l.StartTime.Before(archive.StartFrom) {
+			archive.StartFrom = l.StartTime
+		}
+		if l.StartTime.After(archive.StartTo) {
+			archive.StartTo = l.StartTime
+		}
+	}
+	if err := db.Db.Create(archive).Error; err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// writeArchive 写入归档文件，返回文件大小
+func writeArchive(path string, logs []*model.TaskLog) (int64, error) {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	for _, l := range logs {
+		if err := encoder.Encode(l); err != nil {
+			file.Close()
+			return 0, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, path)
+}
+
+// ListArchives 获取任务的日志归档
+func (r *logRetention) ListArchives(taskID uint) ([]*model.TaskLogArchive, error) {
+	var archives []*model.TaskLogArchive
+	if err := db.Db.Where("task_id = ?", taskID).Order("id desc").Find(&archives).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务日志归档失败, TaskID: %d, 错误: %v", taskID, err))
+		return nil, err
+	}
+	return archives, nil
+}
+
+// ArchivePath 返回任务日志归档文件的本地路径
+func (r *logRetention) ArchivePath(taskID, archiveID uint) (*model.TaskLogArchive, string, error) {
+	var archive model.TaskLogArchive
+	if err := db.Db.Where("task_id = ?", taskID).First(&archive, archiveID).Error; err != nil {
+		return nil, "", ErrArchiveNotFound
+	}
+	path := filepath.Join(r.dir, filepath.FromSlash(archive.FileName))
+	if _, err := os.Stat(path); err != nil {
+		log.Error(fmt.Sprintf("归档文件不存在, ID: %d, 路径: %s, 写入实例: %s, 错误: %v", archive.ID, path, archive.Node, err))
+		return nil, "", fmt.Errorf("%w(由实例 %s 写入，请确认归档目录为共享存储)", ErrArchiveNotFound, archive.Node)
+	}
+	return &archive, path, nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"tools-admin/backend/model"
+)
+
+const (
+	outputBacklogSize    = 1000 // 每次执行保留的最近输出行数
+	outputSubscriberSize = 256  // 订阅者通道缓冲，消费过慢的订阅者会被断开
+)
+
+// 输出流类型
+const (
+	outputStreamStdout = "stdout"
+	outputStreamStderr = "stderr"
+	outputStreamSystem = "system" // 执行开始、结束等系统事件，不计入执行日志
+)
+
+// outputStream 一次执行的实时输出，保留最近的输出行供晚连接的订阅者回放
+type outputStream struct {
+	mu      sync.Mutex
+	backlog []*model.TaskOutputLine // 环形缓冲
+	next    int                     // 缓冲满后下一次写入的位置
+	seq     int64
+	counts  map[int]int // attempt -> 已输出的stdout/stderr行数
+	subs    map[chan *model.TaskOutputLine]struct{}
+	closed  bool
+	done    chan struct{} // 执行结束时关闭，用于区分执行结束与订阅者被断开
+}
+
+func newOutputStream() *outputStream {
+	return &outputStream{
+		counts: make(map[int]int),
+		subs:   make(map[chan *model.TaskOutputLine]struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// emit 发布一行输出
+func (o *outputStream) emit(attempt int, stream, text string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+
+	o.seq++
+	line := &model.TaskOutputLine{
+		Seq:     o.seq,
+		Attempt: attempt,
+		Stream:  stream,
+		Text:    text,
+		Time:    time.Now(),
+	}
+	if stream != outputStreamSystem {
+		o.counts[attempt]++
+	}
+	if len(o.backlog) < outputBacklogSize {
+		o.backlog = append(o.backlog, line)
+	} else {
+		o.backlog[o.next] = line
+		o.next = (o.next + 1) % outputBacklogSize
+	}
+
+	for ch := range o.subs {
+		select {
+		case ch <- line:
+		default:
+			// 订阅者消费过慢，断开以免阻塞执行，done未关闭时订阅者据此判断需要重新订阅
+			delete(o.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// emitText 按行发布一段文本
+func (o *outputStream) emitText(attempt int, stream, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range bytes.Split([]byte(trimTrailingNewline(text)), []byte("\n")) {
+		o.emit(attempt, stream, string(line))
+	}
+}
+
+// emitted 返回某次尝试已输出的stdout/stderr行数
+func (o *outputStream) emitted(attempt int) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.counts[attempt]
+}
+
+// subscribe 订阅输出，返回已缓冲的序号大于after的输出行和后续输出的通道；
+// 执行结束或订阅者消费过慢被断开时通道会被关闭，两者通过 done 区分
+func (o *outputStream) subscribe(after int64) ([]*model.TaskOutputLine, chan *model.TaskOutputLine) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	backlog := make([]*model.TaskOutputLine, 0, len(o.backlog))
+	for _, lines := range [][]*model.TaskOutputLine{o.backlog[o.next:], o.backlog[:o.next]} {
+		for _, line := range lines {
+			if line.Seq > after {
+				backlog = append(backlog, line)
+			}
+		}
+	}
+
+	ch := make(chan *model.TaskOutputLine, outputSubscriberSize)
+	if o.closed {
+		close(ch)
+		return backlog, ch
+	}
+	o.subs[ch] = struct{}{}
+	return backlog, ch
+}
+
+// unsubscribe 取消订阅
+func (o *outputStream) unsubscribe(ch chan *model.TaskOutputLine) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.subs[ch]; ok {
+		delete(o.subs, ch)
+		close(ch)
+	}
+}
+
+// close 结束输出并关闭所有订阅者的通道
+func (o *outputStream) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.closed = true
+	close(o.done)
+	for ch := range o.subs {
+		close(ch)
+	}
+	o.subs = nil
+}
+
+// lineWriter 将写入的内容按行发布到执行的输出流
+type lineWriter struct {
+	run     *TaskRun
+	attempt int
+	stream  string
+	partial []byte
+}
+
+func newLineWriter(run *TaskRun, stream string) *lineWriter {
+	return &lineWriter{run: run, attempt: run.Attempt(), stream: stream}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.run.output.emit(w.attempt, w.stream, string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 发布最后一行不以换行结尾的内容
+func (w *lineWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.run.output.emit(w.attempt, w.stream, string(w.partial))
+		w.partial = nil
+	}
+}
+
+func trimTrailingNewline(text string) string {
+	if n := len(text); n > 0 && text[n-1] == '\n' {
+		return text[:n-1]
+	}
+	return text
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+
+	"tools-admin/backend/common/config"
+	"tools-admin/backend/pkg/log"
+)
+
+// defaultMaxWorkers 未配置 scheduler.max_workers 时同时执行的任务数上限
+const defaultMaxWorkers = 10
+
+// queuedRun 等待执行的任务，按优先级排序，同优先级先进先出
+type queuedRun struct {
+	run  *TaskRun
+	rank int
+	seq  uint64
+}
+
+// runQueue 实现 heap.Interface
+type runQueue []*queuedRun
+
+func (q runQueue) Len() int { return len(q) }
+
+func (q runQueue) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank < q[j].rank
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q runQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *runQueue) Push(x interface{}) { *q = append(*q, x.(*queuedRun)) }
+
+func (q *runQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// workerPool 固定数量的执行协程，超出的执行按优先级排队
+type workerPool struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	queue runQueue
+	seq   uint64
+	size  int
+	busy  int // 正在执行的数量
+	exec  func(run *TaskRun)
+}
+
+// taskPool 全局任务执行池
+var taskPool *workerPool
+
+func init() {
+	taskPool = newWorkerPool(config.Config.Scheduler.MaxWorkers, (&TaskService{}).executeRun)
+}
+
+// newWorkerPool 创建执行池并启动执行协程
+func newWorkerPool(size int, exec func(run *TaskRun)) *workerPool {
+	if size <= 0 {
+		size = defaultMaxWorkers
+	}
+	p := &workerPool{size: size, exec: exec}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// submit 将执行加入队列
+func (p *workerPool) submit(run *TaskRun) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	heap.Push(&p.queue, &queuedRun{run: run, rank: run.Task.PriorityRank(), seq: p.seq})
+	if p.busy >= p.size {
+		log.Info(fmt.Sprintf("执行池已满，任务进入排队, ID: %d, 批次: %s, 排队数: %d", run.Task.ID, run.ID, p.queue.Len()))
+	}
+	p.cond.Signal()
+}
+
+// work 循环取出优先级最高的执行
+func (p *workerPool) work() {
+	for {
+		p.mu.Lock()
+		for p.queue.Len() == 0 {
+			p.cond.Wait()
+		}
+		item := heap.Pop(&p.queue).(*queuedRun)
+		p.busy++
+		p.mu.Unlock()
+
+		p.runSafely(item.run)
+
+		p.mu.Lock()
+		p.busy--
+		p.mu.Unlock()
+	}
+}
+
+// runSafely 执行任务，避免panic导致执行协程退出
+func (p *workerPool) runSafely(run *TaskRun) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("任务执行异常, ID: %d, 批次: %s, 错误: %v", run.Task.ID, run.ID, r))
+		}
+	}()
+	p.exec(run)
+}
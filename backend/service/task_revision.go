@@ -0,0 +1,262 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+var ErrRevisionNotFound = errors.New("任务版本不存在")
+
+// maxDiffCells 逐行对比时去掉首尾相同的行后，两边行数乘积的上限，即对比表的大小(每格4字节，约16MB)，
+// 超过时按整段替换展示
+const maxDiffCells = 4 << 20
+
+// lineDiffFields 需要逐行对比的文本字段
+var lineDiffFields = map[string]bool{
+	"taskContent": true,
+	"taskParams":  true,
+	"description": true,
+}
+
+// recordRevision 在事务中为任务配置的变更记录新版本，配置未变化时不记录。
+// 启用版本记录前创建的任务没有版本，首次修改时先记录修改前的配置作为原始版本
+func (s *TaskService) recordRevision(tx *gorm.DB, task *model.Task, action string, rollbackFrom int, operator model.Operator) error {
+	var current model.Task
+	if err := tx.First(&current, task.ID).Error; err != nil {
+		return err
+	}
+
+	if current.Revision == 0 {
+		current.Revision = 1
+		baseline, err := model.NewTaskRevision(&current, model.RevisionActionBaseline, model.Operator{Username: systemOperator})
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(baseline).Error; err != nil {
+			return err
+		}
+	}
+	task.Revision = current.Revision
+
+	if sameSpec(current.Spec(), task.Spec()) {
+		return nil
+	}
+
+	task.Revision = current.Revision + 1
+	revision, err := model.NewTaskRevision(task, action, operator)
+	if err != nil {
+		return err
+	}
+	revision.RollbackFrom = rollbackFrom
+	return tx.Create(revision).Error
+}
+
+// ListRevisions 获取任务的版本列表，按版本号倒序
+func (s *TaskService) ListRevisions(taskID uint) ([]*model.TaskRevisionResponse, error) {
+	var revisions []*model.TaskRevision
+	if err := db.Db.Where("task_id = ?", taskID).Order("revision desc").Find(&revisions).Error; err != nil {
+		log.Error(fmt.Sprintf("获取任务版本失败, TaskID: %d, 错误: %v", taskID, err))
+		return nil, err
+	}
+
+	responses := make([]*model.TaskRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		responses = append(responses, revision.ToResponse(false))
+	}
+	return responses, nil
+}
+
+// GetRevision 获取任务的某个版本
+func (s *TaskService) GetRevision(taskID uint, revision int) (*model.TaskRevision, error) {
+	var rev model.TaskRevision
+	if err := db.Db.Where("task_id = ? AND revision = ?", taskID, revision).First(&rev).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		log.Error(fmt.Sprintf("获取任务版本失败, TaskID: %d, 版本: %d, 错误: %v", taskID, revision, err))
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// DiffRevisions 对比任务的两个版本，返回有变化的字段
+func (s *TaskService) DiffRevisions(taskID uint, from, to int) (*model.TaskRevisionDiff, error) {
+	fromSpec, err := s.revisionSpec(taskID, from)
+	if err != nil {
+		return nil, err
+	}
+	toSpec, err := s.revisionSpec(taskID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TaskRevisionDiff{
+		TaskID:  taskID,
+		From:    from,
+		To:      to,
+		Changes: diffSpecs(fromSpec, toSpec),
+	}, nil
+}
+
+func (s *TaskService) revisionSpec(taskID uint, revision int) (*model.TaskSpec, error) {
+	rev, err := s.GetRevision(taskID, revision)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := rev.ParseSpec()
+	if err != nil {
+		return nil, fmt.Errorf("解析版本 %d 的配置失败: %v", revision, err)
+	}
+	return spec, nil
+}
+
+// Rollback 将任务配置回滚到指定版本，回滚本身记录为一个新版本
+func (s *TaskService) Rollback(taskID uint, revision int, operator model.Operator) (*model.Task, error) {
+	spec, err := s.revisionSpec(taskID, revision)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.GetByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	task.ApplySpec(spec)
+	if err := s.save(task, model.RevisionActionRollback, revision, operator); err != nil {
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("任务已回滚, ID: %d, 回滚到版本: %d, 新版本: %d, 操作人: %s", taskID, revision, task.Revision, operator.Username))
+	return task, nil
+}
+
+// diffSpecs 按字段对比两份配置，字段名使用JSON名称
+func diffSpecs(from, to *model.TaskSpec) []*model.TaskFieldDiff {
+	changes := make([]*model.TaskFieldDiff, 0)
+	fromValue, toValue := reflect.ValueOf(from).Elem(), reflect.ValueOf(to).Elem()
+	specType := fromValue.Type()
+	for i := 0; i < specType.NumField(); i++ {
+		a, b := fromValue.Field(i).Interface(), toValue.Field(i).Interface()
+		if sameValue(a, b) {
+			continue
+		}
+		field := strings.Split(specType.Field(i).Tag.Get("json"), ",")[0]
+		change := &model.TaskFieldDiff{Field: field, From: a, To: b}
+		if lineDiffFields[field] {
+			change.Lines = lineDiff(a.(string), b.(string))
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// sameSpec 判断两份配置是否相同，只比较字段值，不做逐行对比
+func sameSpec(from, to *model.TaskSpec) bool {
+	fromValue, toValue := reflect.ValueOf(from).Elem(), reflect.ValueOf(to).Elem()
+	for i := 0; i < fromValue.NumField(); i++ {
+		if !sameValue(fromValue.Field(i).Interface(), toValue.Field(i).Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue 判断字段值是否相同，空列表和未设置的列表视为相同
+func sameValue(a, b interface{}) bool {
+	if x, ok := a.(model.IDList); ok {
+		y := b.(model.IDList)
+		if len(x) == 0 && len(y) == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// lineDiff 基于最长公共子序列的逐行对比，首尾相同的行不参与计算
+func lineDiff(from, to string) []*model.DiffLine {
+	a, b := splitLines(from), splitLines(to)
+	lines := make([]*model.DiffLine, 0, len(a)+len(b))
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, text := range a[:prefix] {
+		lines = append(lines, &model.DiffLine{Op: model.DiffLineEqual, Text: text})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, &model.DiffLine{Op: model.DiffLineEqual, Text: text})
+	}
+	return lines
+}
+
+// diffMiddle 对比去掉首尾相同行后的部分，超过 maxDiffCells 时按整段替换展示
+func diffMiddle(a, b []string) []*model.DiffLine {
+	lines := make([]*model.DiffLine, 0, len(a)+len(b))
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, text := range a {
+			lines = append(lines, &model.DiffLine{Op: model.DiffLineDelete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, &model.DiffLine{Op: model.DiffLineInsert, Text: text})
+		}
+		return lines
+	}
+
+	// lcs[i*width+j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	width := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, &model.DiffLine{Op: model.DiffLineEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			lines = append(lines, &model.DiffLine{Op: model.DiffLineDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, &model.DiffLine{Op: model.DiffLineInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, &model.DiffLine{Op: model.DiffLineDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, &model.DiffLine{Op: model.DiffLineInsert, Text: b[j]})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(trimTrailingNewline(text), "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+)
+
+var (
+	ErrRunNotFound  = errors.New("执行不存在或已结束")
+	ErrTaskRunning  = errors.New("任务上一次执行尚未结束")
+	ErrRenderFailed = errors.New("任务模板渲染失败")
+)
+
+// systemOperator 由系统发起取消时记录的操作人
+const systemOperator = "system"
+
+// TaskRun 一次任务执行的上下文，重试时多次尝试共用同一个TaskRun
+type TaskRun struct {
+	ID            string // 执行批次ID
+	Task          *model.Task
+	StartTime     time.Time
+	ScheduledTime time.Time              // 计划执行时间，手动执行时为提交时间
+	Params        map[string]interface{} // 手动执行时传入的参数
+
+	runParams       string // 合并默认值后的参数(JSON)
+	renderedContent string // 渲染后的任务内容，不含模板时为空
+	renderedParams  string // 渲染后的任务参数，不含模板时为空
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	output *outputStream // 实时输出
+	done   chan struct{} // 执行结束后关闭
+
+	mu          sync.Mutex
+	started     bool      // 已从队列取出开始执行
+	startedAt   time.Time // 开始执行的时间
+	attempt     int
+	cancelledBy string
+}
+
+// newTaskRun 创建一次任务执行
+func newTaskRun(task *model.Task, scheduledTime time.Time, params map[string]interface{}) *TaskRun {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TaskRun{
+		ID:            newRunID(),
+		Task:          task,
+		StartTime:     time.Now(),
+		ScheduledTime: scheduledTime,
+		Params:        params,
+		ctx:           ctx,
+		cancel:        cancel,
+		output:        newOutputStream(),
+		done:          make(chan struct{}),
+	}
+}
+
+// newRunID 生成执行批次ID：时间戳加随机数
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
+
+// markCancelled 记录取消人，重复取消时返回false
+func (r *TaskRun) markCancelled(operator string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancelledBy != "" {
+		return false
+	}
+	r.cancelledBy = operator
+	return true
+}
+
+// CancelledBy 返回取消人，未取消时返回空字符串
+func (r *TaskRun) CancelledBy() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelledBy
+}
+
+func (r *TaskRun) markStarted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = true
+	r.startedAt = time.Now()
+}
+
+// StartedAt 返回开始执行的时间，仍在排队时ok为false
+func (r *TaskRun) StartedAt() (startedAt time.Time, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.startedAt, r.started
+}
+
+func (r *TaskRun) setAttempt(attempt int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempt = attempt
+}
+
+// Attempt 返回当前第几次尝试
+func (r *TaskRun) Attempt() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempt
+}
+
+// Info 返回执行信息
+func (r *TaskRun) Info() *model.TaskRunInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &model.TaskRunInfo{
+		RunID:         r.ID,
+		TaskID:        r.Task.ID,
+		Queued:        !r.started,
+		ScheduledTime: r.ScheduledTime,
+		Attempt:       r.attempt,
+		StartTime:     r.StartTime,
+		CancelledBy:   r.cancelledBy,
+	}
+}
+
+// runRegistry 本实例上正在进行的执行
+type runRegistry struct {
+	mu   sync.RWMutex
+	runs map[string]*TaskRun
+}
+
+var taskRuns = &runRegistry{runs: make(map[string]*TaskRun)}
+
+// admit 按任务的并发策略登记新的执行，返回该任务尚未结束的执行；
+// 策略为禁止并行且有执行未结束时不登记，ok为false
+func (r *runRegistry) admit(run *TaskRun) (active []*TaskRun, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.runs {
+		if other.Task.ID == run.Task.ID {
+			active = append(active, other)
+		}
+	}
+	if len(active) > 0 && run.Task.ConcurrencyPolicy == model.ConcurrencyForbid {
+		return active, false
+	}
+	r.runs[run.ID] = run
+	return active, true
+}
+
+func (r *runRegistry) remove(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, runID)
+}
+
+func (r *runRegistry) get(runID string) (*TaskRun, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	run, ok := r.runs[runID]
+	return run, ok
+}
+
+// all 返回所有正在进行的执行
+func (r *runRegistry) all() []*TaskRun {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	runs := make([]*TaskRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// listByTask 返回任务正在进行的执行，按开始时间排序
+func (r *runRegistry) listByTask(taskID uint) []*TaskRun {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var runs []*TaskRun
+	for _, run := range r.runs {
+		if run.Task.ID == taskID {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartTime.Before(runs[j].StartTime)
+	})
+	return runs
+}
+
+// dispatch 使用参数params渲染任务内容后，按任务的并发策略提交一次计划在scheduledTime的执行到执行池。
+// 渲染失败时记录一条失败日志并返回错误；禁止并行且上一次执行未结束时记录一条跳过日志并返回 ErrTaskRunning；
+// 替换策略下会先取消该任务尚未结束的执行
+func (s *TaskService) dispatch(task *model.Task, scheduledTime time.Time, params map[string]interface{}) (*TaskRun, error) {
+	run := newTaskRun(task, scheduledTime, params)
+	if err := renderRun(run); err != nil {
+		run.cancel()
+		now := time.Now()
+		s.saveRunLog(run, 1, false, &ExecResult{
+			Status:      model.TaskExecStatusFailed,
+			FailureKind: model.FailureKindOther,
+			Error:       err.Error(),
+			ExitCode:    -1,
+		}, now, now)
+		return nil, fmt.Errorf("%w: %v", ErrRenderFailed, err)
+	}
+
+	active, ok := taskRuns.admit(run)
+	if !ok {
+		run.cancel()
+		s.saveSkippedLog(task, fmt.Sprintf("上一次执行(批次 %s)尚未结束，按并发策略跳过本次执行", active[0].ID))
+		return nil, ErrTaskRunning
+	}
+
+	if task.ConcurrencyPolicy == model.ConcurrencyReplace {
+		for _, old := range active {
+			log.Info(fmt.Sprintf("按并发策略替换旧执行, ID: %d, 旧批次: %s, 新批次: %s", task.ID, old.ID, run.ID))
+			if err := s.cancelRun(old, systemOperator); err != nil {
+				log.Error(fmt.Sprintf("取消旧执行失败, ID: %d, 批次: %s, 错误: %v", task.ID, old.ID, err))
+			}
+		}
+	}
+
+	taskPool.submit(run)
+	return run, nil
+}
+
+// executeRun 执行任务并记录执行日志，失败时按任务的重试策略重试
+func (s *TaskService) executeRun(run *TaskRun) {
+	task := run.Task
+	defer close(run.done)
+	defer taskRuns.remove(run.ID)
+	defer run.output.close()
+	defer run.cancel()
+	run.markStarted()
+
+	// 排队期间被取消，不再执行
+	if run.ctx.Err() != nil {
+		now := time.Now()
+		s.saveRunLog(run, 1, false, &ExecResult{
+			Status:   model.TaskExecStatusCancelled,
+			Error:    fmt.Sprintf("排队期间执行已被 %s 取消", run.CancelledBy()),
+			ExitCode: -1,
+		}, now, now)
+		return
+	}
+
+	// 更新执行状态为执行中，并记录上次执行时间
+	now := time.Now()
+	task.ExecStatus = model.TaskExecStatusRunning
+	task.LastRunTime = &now
+	if err := db.Db.Model(task).Updates(map[string]interface{}{
+		"exec_status":   task.ExecStatus,
+		"last_run_time": now,
+	}).Error; err != nil {
+		log.Error(fmt.Sprintf("更新任务执行状态失败, ID: %d, 错误: %v", task.ID, err))
+		return
+	}
+	go TaskAlerter.onRunStarted(run)
+
+	var result *ExecResult
+attempts:
+	for attempt := 1; ; attempt++ {
+		run.setAttempt(attempt)
+		run.output.emit(attempt, outputStreamSystem, fmt.Sprintf("第%d次执行开始", attempt))
+		startTime := time.Now()
+		result = s.runExecutor(run)
+		endTime := time.Now()
+
+		// 执行器没有实时输出时（如HTTP任务），在结束时一次性发布结果
+		if run.output.emitted(attempt) == 0 {
+			run.output.emitText(attempt, outputStreamStdout, result.Output)
+			run.output.emitText(attempt, outputStreamStderr, result.Error)
+		}
+		if operator := run.CancelledBy(); operator != "" {
+			message := fmt.Sprintf("执行已被 %s 取消", operator)
+			result.Status = model.TaskExecStatusCancelled
+			result.Error = appendLine(result.Error, message)
+			run.output.emit(attempt, outputStreamStderr, message)
+		}
+		run.output.emit(attempt, outputStreamSystem, fmt.Sprintf("第%d次执行结束，状态: %s", attempt, result.Status))
+
+		retry := result.Status == model.TaskExecStatusFailed && task.ShouldRetry(attempt, result.FailureKind)
+		s.saveRunLog(run, attempt, retry, result, startTime, endTime)
+		if !retry {
+			break
+		}
+
+		delay := task.RetryDelay(attempt)
+		log.Info(fmt.Sprintf("任务将在 %s 后重试, ID: %d, 批次: %s, 失败类型: %s", delay, task.ID, run.ID, result.FailureKind))
+		select {
+		case <-time.After(delay):
+		case <-run.ctx.Done():
+			// 等待重试期间被取消，记录一条取消结果作为最终结果
+			now := time.Now()
+			result = &ExecResult{
+				Status:   model.TaskExecStatusCancelled,
+				Error:    fmt.Sprintf("等待重试期间执行已被 %s 取消", run.CancelledBy()),
+				ExitCode: -1,
+			}
+			s.saveRunLog(run, attempt+1, false, result, now, now)
+			break attempts
+		}
+	}
+
+	task.ExecStatus = result.Status
+	if err := db.Db.Model(task).Update("exec_status", task.ExecStatus).Error; err != nil {
+		log.Error(fmt.Sprintf("更新任务执行状态失败, ID: %d, 错误: %v", task.ID, err))
+	}
+
+	go TaskAlerter.onRunFinished(run, result)
+
+	// 检查并触发下游任务
+	TaskScheduler.triggerDownstream(task.ID)
+}
+
+// saveRunLog 保存一次尝试的执行日志
+func (s *TaskService) saveRunLog(run *TaskRun, attempt int, retried bool, result *ExecResult, startTime, endTime time.Time) {
+	taskLog := &model.TaskLog{
+		TaskID:          run.Task.ID,
+		RunID:           run.ID,
+		Revision:        run.Task.Revision,
+		Attempt:         attempt,
+		Retried:         retried,
+		FailureKind:     result.FailureKind,
+		Status:          result.Status,
+		Output:          result.Output,
+		Error:           result.Error,
+		ExitCode:        result.ExitCode,
+		Metrics:         result.Metrics,
+		RunParams:       run.runParams,
+		RenderedContent: run.renderedContent,
+		RenderedParams:  run.renderedParams,
+		StartTime:       startTime,
+		EndTime:         endTime,
+		Duration:        int64(endTime.Sub(startTime).Seconds()),
+	}
+	if result.Status == model.TaskExecStatusCancelled {
+		taskLog.CancelledBy = run.CancelledBy()
+	}
+	if err := db.Db.Create(taskLog).Error; err != nil {
+		log.Error(fmt.Sprintf("保存任务日志失败, ID: %d, 错误: %v", run.Task.ID, err))
+	} else {
+		TaskStats.record(taskLog, run.Task.Type)
+	}
+	log.Info(fmt.Sprintf("任务执行完成, ID: %d, 批次: %s, 第%d次, 状态: %s, 耗时: %s",
+		run.Task.ID, run.ID, attempt, result.Status, endTime.Sub(startTime)))
+}
+
+// saveSkippedLog 记录一次未执行的调度
+func (s *TaskService) saveSkippedLog(task *model.Task, reason string) {
+	now := time.Now()
+	taskLog := &model.TaskLog{
+		TaskID:    task.ID,
+		RunID:     newRunID(),
+		Revision:  task.Revision,
+		Attempt:   1,
+		Status:    model.TaskExecStatusSkipped,
+		Error:     reason,
+		StartTime: now,
+		EndTime:   now,
+	}
+	if err := db.Db.Create(taskLog).Error; err != nil {
+		log.Error(fmt.Sprintf("保存任务日志失败, ID: %d, 错误: %v", task.ID, err))
+	}
+	log.Info(fmt.Sprintf("任务本次未执行, ID: %d, 原因: %s", task.ID, reason))
+}
+
+// runExecutor 调用任务类型对应的执行器，执行器内部的panic视为执行失败
+func (s *TaskService) runExecutor(run *TaskRun) (result *ExecResult) {
+	task := run.Task
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("任务执行异常, ID: %d, 错误: %v", task.ID, r))
+			result = failedResult("任务执行异常: %v", r)
+		}
+	}()
+
+	executor, ok := GetExecutor(task.Type)
+	if !ok {
+		return failedResult("不支持的任务类型: %s", task.Type)
+	}
+	return executor.Run(run.ctx, run)
+}
+
+// ListRuns 获取任务正在进行的执行
+func (s *TaskService) ListRuns(taskID uint) []*model.TaskRunInfo {
+	runs := taskRuns.listByTask(taskID)
+	infos := make([]*model.TaskRunInfo, 0, len(runs))
+	for _, run := range runs {
+		infos = append(infos, run.Info())
+	}
+	return infos
+}
+
+// SubscribeOutput 订阅一次执行的实时输出，返回已缓冲的序号大于after的输出、后续输出的通道、
+// 执行结束时关闭的done通道和取消订阅的函数。输出通道在执行结束或订阅者消费过慢被断开时关闭，
+// 关闭时done未关闭说明执行仍在进行，应重新订阅
+func (s *TaskService) SubscribeOutput(taskID uint, runID string, after int64) ([]*model.TaskOutputLine, <-chan *model.TaskOutputLine, <-chan struct{}, func(), error) {
+	run, ok := taskRuns.get(runID)
+	if !ok || run.Task.ID != taskID {
+		return nil, nil, nil, nil, ErrRunNotFound
+	}
+	backlog, ch := run.output.subscribe(after)
+	return backlog, ch, run.output.done, func() { run.output.unsubscribe(ch) }, nil
+}
+
+// CancelRun 取消任务的一次执行
+func (s *TaskService) CancelRun(taskID uint, runID string, operator string) error {
+	run, ok := taskRuns.get(runID)
+	if !ok || run.Task.ID != taskID {
+		return ErrRunNotFound
+	}
+	return s.cancelRun(run, operator)
+}
+
+// cancelRun 记录取消人并通知执行器取消，排队中的执行会在出队时直接结束
+func (s *TaskService) cancelRun(run *TaskRun, operator string) error {
+	if !run.markCancelled(operator) {
+		return fmt.Errorf("执行已被 %s 取消", run.CancelledBy())
+	}
+
+	log.Info(fmt.Sprintf("取消任务执行, ID: %d, 批次: %s, 操作人: %s", run.Task.ID, run.ID, operator))
+	executor, ok := GetExecutor(run.Task.Type)
+	if !ok {
+		run.cancel()
+		return nil
+	}
+	return executor.Cancel(run)
+}
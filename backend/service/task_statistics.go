@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/db"
+	"tools-admin/backend/pkg/log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	statsDateLayout     = "2006-01-02"
+	maxStatsRebuildDays = 366
+)
+
+// ErrStatsLogsPurged 重建范围内的日志已被归档清理
+var ErrStatsLogsPurged = errors.New("范围内的部分日志已被归档清理，重建会丢失这些日志的统计")
+
+// TaskStats 全局任务执行统计
+var TaskStats = &taskStatistics{}
+
+// taskStatistics 维护每日任务执行统计。执行批次的最终结果写入日志时增量累加，
+// 只统计成功、失败和取消，跳过的调度和会重试的失败不计入
+type taskStatistics struct{}
+
+// statsCounts 一条日志对各计数的增量
+type statsCounts struct {
+	total, success, fail, cancel int
+}
+
+// countLog 返回日志对统计的增量，不计入统计时返回false
+func countLog(taskLog *model.TaskLog) (statsCounts, bool) {
+	if !taskLog.IsFinal() {
+		return statsCounts{}, false
+	}
+	switch taskLog.Status {
+	case model.TaskExecStatusSuccess:
+		return statsCounts{total: 1, success: 1}, true
+	case model.TaskExecStatusFailed:
+		return statsCounts{total: 1, fail: 1}, true
+	case model.TaskExecStatusCancelled:
+		return statsCounts{total: 1, cancel: 1}, true
+	}
+	return statsCounts{}, false
+}
+
+// successRate 计算成功率(百分比)，保留两位小数
+func successRate(success, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(success*10000/total) / 100
+}
+
+// statsDate 返回时间所在的统计日期
+func statsDate(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// record 将一条执行日志计入统计，按日志的开始时间归入日期
+func (s *taskStatistics) record(taskLog *model.TaskLog, taskType model.TaskType) {
+	c, ok := countLog(taskLog)
+	if !ok {
+		return
+	}
+	date := statsDate(taskLog.StartTime)
+
+	daily := &model.TaskStatistics{
+		Date:         date,
+		TotalCount:   c.total,
+		SuccessCount: c.success,
+		FailCount:    c.fail,
+		CancelCount:  c.cancel,
+		SuccessRate:  successRate(c.success, c.total),
+	}
+	if err := db.Db.Clauses(incrementClause(c, "date")).Create(daily).Error; err != nil {
+		log.Error(fmt.Sprintf("更新任务统计失败, 日期: %s, 错误: %v", date.Format(statsDateLayout), err))
+	}
+
+	detail := &model.TaskDailyStatistics{
+		Date:         date,
+		TaskID:       taskLog.TaskID,
+		TaskType:     taskType,
+		TotalCount:   c.total,
+		SuccessCount: c.success,
+		FailCount:    c.fail,
+		CancelCount:  c.cancel,
+		SuccessRate:  successRate(c.success, c.total),
+	}
+	if err := db.Db.Clauses(incrementClause(c, "date", "task_id")).Create(detail).Error; err != nil {
+		log.Error(fmt.Sprintf("更新任务统计失败, 日期: %s, TaskID: %d, 错误: %v",
+			date.Format(statsDateLayout), taskLog.TaskID, err))
+	}
+}
+
+// incrementClause 统计记录已存在时累加计数并重新计算成功率。
+// MySQL按顺序执行赋值，成功率需在计数之后计算
+func incrementClause(c statsCounts, columns ...string) clause.OnConflict {
+	conflict := clause.OnConflict{
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "total_count"}, Value: gorm.Expr("total_count + ?", c.total)},
+			{Column: clause.Column{Name: "success_count"}, Value: gorm.Expr("success_count + ?", c.success)},
+			{Column: clause.Column{Name: "fail_count"}, Value: gorm.Expr("fail_count + ?", c.fail)},
+			{Column: clause.Column{Name: "cancel_count"}, Value: gorm.Expr("cancel_count + ?", c.cancel)},
+			{Column: clause.Column{Name: "success_rate"}, Value: gorm.Expr("TRUNCATE(success_count * 100 / total_count, 2)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: time.Now()},
+		},
+	}
+	for _, name := range columns {
+		conflict.Columns = append(conflict.Columns, clause.Column{Name: name})
+	}
+	return conflict
+}
+
+// statsRow 重建时按日期和任务汇总的日志计数
+type statsRow struct {
+	Date         time.Time
+	TaskID       uint
+	TaskType     model.TaskType
+	SuccessCount int
+	FailCount    int
+	CancelCount  int
+}
+
+// Rebuild 根据执行日志重新计算日期范围内(含首尾)的统计。范围内有已归档清理的日志时，
+// 除非force为true，否则拒绝重建，避免清理后的统计被覆盖为更小的值。
+// 重建与增量累加不互斥，重建当天的统计时可能重复或遗漏重建期间完成的执行
+func (s *taskStatistics) Rebuild(from, to time.Time, force bool) (*model.TaskStatisticsRebuildResult, error) {
+	from, to = statsDate(from), statsDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("结束日期不能早于开始日期")
+	}
+	end := to.AddDate(0, 0, 1)
+	if end.Sub(from) > maxStatsRebuildDays*24*time.Hour {
+		return nil, fmt.Errorf("重建范围不能超过%d天", maxStatsRebuildDays)
+	}
+
+	if !force {
+		var purged int64
+		if err := db.Db.Model(&model.TaskLogArchive{}).Where("start_from < ? AND start_to >= ?", end, from).
+			Count(&purged).Error; err != nil {
+			return nil, err
+		}
+		if purged > 0 {
+			return nil, ErrStatsLogsPurged
+		}
+	}
+
+	// 任务类型取任务当前的类型，已删除的任务同样统计
+	var rows []*statsRow
+	if err := db.Db.Table("task_logs AS l").
+		Select(`DATE(l.start_time) AS date, l.task_id, COALESCE(MAX(t.type), 0) AS task_type,
+			SUM(l.status = ?) AS success_count, SUM(l.status = ?) AS fail_count, SUM(l.status = ?) AS cancel_count`,
+			model.TaskExecStatusSuccess, model.TaskExecStatusFailed, model.TaskExecStatusCancelled).
+		Joins("LEFT JOIN tasks AS t ON t.id = l.task_id").
+		Where("l.deleted_at IS NULL AND l.retried = ? AND l.status IN ?", false, []model.TaskExecStatus{
+			model.TaskExecStatusSuccess, model.TaskExecStatusFailed, model.TaskExecStatusCancelled,
+		}).
+		Where("l.start_time >= ? AND l.start_time < ?", from, end).
+		Group("DATE(l.start_time), l.task_id").
+		Scan(&rows).Error; err != nil {
+		log.Error(fmt.Sprintf("汇总任务日志失败: %v", err))
+		return nil, err
+	}
+
+	details := make([]*model.TaskDailyStatistics, 0, len(rows))
+	dailyMap := make(map[string]*model.TaskStatistics)
+	result := &model.TaskStatisticsRebuildResult{
+		From:  from.Format(statsDateLayout),
+		To:    to.Format(statsDateLayout),
+		Tasks: len(rows),
+	}
+	for _, row := range rows {
+		date := statsDate(row.Date)
+		total := row.SuccessCount + row.FailCount + row.CancelCount
+		details = append(details, &model.TaskDailyStatistics{
+			Date:         date,
+			TaskID:       row.TaskID,
+			TaskType:     row.TaskType,
+			TotalCount:   total,
+			SuccessCount: row.SuccessCount,
+			FailCount:    row.FailCount,
+			CancelCount:  row.CancelCount,
+			SuccessRate:  successRate(row.SuccessCount, total),
+		})
+
+		key := date.Format(statsDateLayout)
+		daily, ok := dailyMap[key]
+		if !ok {
+			daily = &model.TaskStatistics{Date: date}
+			dailyMap[key] = daily
+		}
+		daily.TotalCount += total
+		daily.SuccessCount += row.SuccessCount
+		daily.FailCount += row.FailCount
+		daily.CancelCount += row.CancelCount
+		result.Runs += total
+	}
+	dailies := make([]*model.TaskStatistics, 0, len(dailyMap))
+	for _, daily := range dailyMap {
+		daily.SuccessRate = successRate(daily.SuccessCount, daily.TotalCount)
+		dailies = append(dailies, daily)
+	}
+	sort.Slice(dailies, func(i, j int) bool { return dailies[i].Date.Before(dailies[j].Date) })
+	result.Days = len(dailies)
+
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("date >= ? AND date <= ?", from, to).Delete(&model.TaskStatistics{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("date >= ? AND date <= ?", from, to).Delete(&model.TaskDailyStatistics{}).Error; err != nil {
+			return err
+		}
+		if len(dailies) > 0 {
+			if err := tx.Create(&dailies).Error; err != nil {
+				return err
+			}
+		}
+		if len(details) > 0 {
+			if err := tx.CreateInBatches(&details, 500).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("重建任务统计失败, 范围: %s ~ %s, 错误: %v", result.From, result.To, err))
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("重建任务统计完成, 范围: %s ~ %s, 天数: %d, 执行批次: %d",
+		result.From, result.To, result.Days, result.Runs))
+	return result, nil
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"tools-admin/backend/model"
+	"tools-admin/backend/pkg/cronutil"
+)
+
+const (
+	templateDateLayout     = "2006-01-02"
+	templateDateTimeLayout = "2006-01-02 15:04:05"
+
+	// 模板使用 ${{ }} 作为定界符，它在Shell中不是合法语法，任务内容中的 {{ }}
+	// (如 docker ps --format '{{.Names}}') 和Shell条件判断 [[ ]] 原样保留
+	templateLeftDelim  = "${{"
+	templateRightDelim = "}}"
+)
+
+// shellSafe 无需加引号即可在Shell中原样使用的字符
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote 将值转换为Shell中的单个参数，含特殊字符时加单引号
+func shellQuote(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" {
+		return "''"
+	}
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jsonEscape 将值转义为JSON字符串的内容(不含两侧引号)
+func jsonEscape(v interface{}) string {
+	data, _ := json.Marshal(fmt.Sprint(v))
+	return string(data[1 : len(data)-1])
+}
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = template.FuncMap{
+	// shellquote 转义为Shell参数，Shell任务内容中的输出默认使用
+	"shellquote": shellQuote,
+	// jsonescape 转义为JSON字符串内容，任务参数中的输出默认使用
+	"jsonescape": jsonEscape,
+	// raw 原样输出，不做默认转义，如 ${{raw .where}}，仅用于可信的参数
+	"raw": func(v interface{}) interface{} {
+		return v
+	},
+	// add_days 日期加减天数，如 ${{add_days .today -7}}
+	"add_days": func(date string, days int) (string, error) {
+		t, err := time.Parse(templateDateLayout, date)
+		if err != nil {
+			return "", err
+		}
+		return t.AddDate(0, 0, days).Format(templateDateLayout), nil
+	},
+	// nodash 去掉日期中的横线，如 ${{nodash .yesterday}} 得到 20240101
+	"nodash": func(date string) string {
+		return strings.ReplaceAll(date, "-", "")
+	},
+}
+
+// taskParamDefaults 从 TaskParams 的 params 字段读取参数默认值
+func taskParamDefaults(task *model.Task) map[string]interface{} {
+	defaults := make(map[string]interface{})
+	if strings.TrimSpace(task.TaskParams) == "" {
+		return defaults
+	}
+	var params struct {
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(task.TaskParams), &params); err != nil {
+		// 参数不是JSON对象时没有默认值
+		return defaults
+	}
+	for k, v := range params.Params {
+		defaults[k] = v
+	}
+	return defaults
+}
+
+// templateVars 构造模板变量：内置变量、TaskParams 中的默认参数和本次执行传入的参数，后者优先。
+// 传入 scheduled_time 时以其为计划时间计算 today、yesterday 等日期，便于补数据
+func templateVars(run *TaskRun) (map[string]interface{}, map[string]interface{}, error) {
+	task := run.Task
+	params := taskParamDefaults(task)
+	for k, v := range run.Params {
+		params[k] = v
+	}
+
+	loc, err := cronutil.LoadLocation(task.Timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	scheduled := run.ScheduledTime.In(loc)
+	if v, ok := params["scheduled_time"]; ok {
+		if scheduled, err = parseTemplateTime(fmt.Sprint(v), loc); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	vars := map[string]interface{}{
+		"run_id":         run.ID,
+		"task_id":        task.ID,
+		"task_name":      task.Name,
+		"scheduled_time": scheduled.Format(templateDateTimeLayout),
+		"timestamp":      scheduled.Unix(),
+		"today":          scheduled.Format(templateDateLayout),
+		"yesterday":      scheduled.AddDate(0, 0, -1).Format(templateDateLayout),
+		"tomorrow":       scheduled.AddDate(0, 0, 1).Format(templateDateLayout),
+	}
+	for k, v := range params {
+		if k == "scheduled_time" || k == "run_id" {
+			continue
+		}
+		vars[k] = v
+	}
+	return vars, params, nil
+}
+
+// parseTemplateTime 解析传入的计划时间，支持日期或日期时间
+func parseTemplateTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{templateDateTimeLayout, templateDateLayout, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.In(loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的计划时间: %s", value)
+}
+
+// parseTemplate 解析模板，escaper 不为空时在每个输出动作的末尾追加该转义函数，
+// 已显式使用 raw 或该转义函数的动作除外
+func parseTemplate(name, text, escaper string) (*template.Template, error) {
+	tmpl, err := template.New(name).Delims(templateLeftDelim, templateRightDelim).
+		Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if escaper != "" {
+		escapeActions(tmpl.Tree.Root, escaper)
+	}
+	return tmpl, nil
+}
+
+// escapeActions 为节点下所有输出值的动作追加转义函数
+func escapeActions(node parse.Node, escaper string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(child, escaper)
+		}
+	case *parse.IfNode:
+		escapeActions(n.List, escaper)
+		escapeActions(n.ElseList, escaper)
+	case *parse.RangeNode:
+		escapeActions(n.List, escaper)
+		escapeActions(n.ElseList, escaper)
+	case *parse.WithNode:
+		escapeActions(n.List, escaper)
+		escapeActions(n.ElseList, escaper)
+	case *parse.ActionNode:
+		// 变量声明不输出
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		cmds := n.Pipe.Cmds
+		if last := cmds[len(cmds)-1]; len(last.Args) > 0 {
+			if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "raw" || ident.Ident == escaper) {
+				return
+			}
+		}
+		n.Pipe.Cmds = append(cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escaper).SetPos(n.Pos)},
+		})
+	}
+}
+
+// renderTemplate 渲染模板，不含模板语法时原样返回；引用不存在的变量时报错
+func renderTemplate(name, text, escaper string, vars map[string]interface{}) (string, error) {
+	if !strings.Contains(text, templateLeftDelim) {
+		return text, nil
+	}
+	tmpl, err := parseTemplate(name, text, escaper)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// contentEscaper 任务内容的默认转义函数：Shell脚本中的参数值加引号，避免注入命令；
+// HTTP任务的请求定义等JSON内容转义为JSON字符串内容，避免破坏结构或注入字段
+func contentEscaper(task *model.Task) string {
+	if task.Type == model.TaskTypeShell {
+		return "shellquote"
+	}
+	trimmed := strings.TrimSpace(task.TaskContent)
+	if task.Type == model.TaskTypeHttp || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return "jsonescape"
+	}
+	return ""
+}
+
+// checkShellPlaceholders 检查Shell脚本中模板变量的位置。变量的值会自动加单引号，
+// 只能作为独立的参数使用：放在引号或heredoc中时单引号不起作用，值中的 $(...) 等仍会被执行。
+// 使用 raw 的变量不检查
+func checkShellPlaceholders(text string) error {
+	const (
+		stateNormal = iota
+		stateSingle
+		stateDouble
+	)
+	state := stateNormal
+	var heredocs []string // 当前行结束后开始的heredoc结束符
+	line := 1
+	for i := 0; i < len(text); i++ {
+		if strings.HasPrefix(text[i:], templateLeftDelim) {
+			end := strings.Index(text[i:], templateRightDelim)
+			if end < 0 {
+				return nil
+			}
+			action := strings.TrimSpace(text[i+len(templateLeftDelim) : i+end])
+			if state != stateNormal && !strings.HasPrefix(action, "raw ") && !strings.HasSuffix(action, "| raw") {
+				return fmt.Errorf("第%d行的模板变量 %s 不能放在引号中，变量的值会自动加引号，请去掉两侧的引号",
+					line, text[i:i+end+len(templateRightDelim)])
+			}
+			i += end + len(templateRightDelim) - 1
+			continue
+		}
+
+		c := text[i]
+		if c == '\n' {
+			line++
+		}
+		switch state {
+		case stateSingle:
+			if c == '\'' {
+				state = stateNormal
+			}
+		case stateDouble:
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				state = stateNormal
+			}
+		default:
+			switch {
+			case c == '\\':
+				i++
+			case c == '\'':
+				state = stateSingle
+			case c == '"':
+				state = stateDouble
+			case c == '#' && (i == 0 || strings.ContainsRune(" \t\n;", rune(text[i-1]))):
+				// 注释到行尾
+				for i+1 < len(text) && text[i+1] != '\n' {
+					i++
+				}
+			case strings.HasPrefix(text[i:], "<<") && !strings.HasPrefix(text[i:], "<<<"):
+				delim, n := heredocDelimiter(text[i+2:])
+				if delim != "" {
+					heredocs = append(heredocs, delim)
+				}
+				i += 1 + n
+			case c == '\n' && len(heredocs) > 0:
+				// 跳过heredoc内容，其中不能使用模板变量
+				for _, delim := range heredocs {
+					for i+1 < len(text) {
+						end := strings.IndexByte(text[i+1:], '\n')
+						if end < 0 {
+							end = len(text) - i - 1
+						}
+						body := text[i+1 : i+1+end]
+						if strings.Contains(body, templateLeftDelim) && !strings.Contains(body, "raw ") {
+							return fmt.Errorf("第%d行的模板变量不能放在heredoc中，请先赋值给变量再使用", line)
+						}
+						i += end + 1
+						line++
+						if strings.TrimLeft(body, "\t") == delim {
+							break
+						}
+					}
+				}
+				heredocs = nil
+			}
+		}
+	}
+	return nil
+}
+
+// heredocDelimiter 解析 << 之后的heredoc结束符，返回结束符和消耗的字节数
+func heredocDelimiter(text string) (string, int) {
+	n := 0
+	if strings.HasPrefix(text, "-") {
+		n++
+	}
+	for n < len(text) && (text[n] == ' ' || text[n] == '\t') {
+		n++
+	}
+	start := n
+	for n < len(text) && !strings.ContainsRune(" \t\n;|&<>)", rune(text[n])) {
+		n++
+	}
+	return strings.Trim(text[start:n], `'"\`), n
+}
+
+// validateTemplate 校验任务内容和参数的模板语法，以及Shell脚本中模板变量的位置
+func validateTemplate(task *model.Task) error {
+	for name, text := range map[string]string{"任务内容": task.TaskContent, "任务参数": task.TaskParams} {
+		if !strings.Contains(text, templateLeftDelim) {
+			continue
+		}
+		if _, err := parseTemplate(name, text, ""); err != nil {
+			return fmt.Errorf("%s模板语法错误: %v", name, err)
+		}
+	}
+	if task.Type == model.TaskTypeShell && strings.Contains(task.TaskContent, templateLeftDelim) {
+		return checkShellPlaceholders(task.TaskContent)
+	}
+	return nil
+}
+
+// renderRun 渲染本次执行的任务内容和参数，run.Task 替换为渲染后的副本，
+// 渲染结果记录在执行日志中便于复现
+func renderRun(run *TaskRun) error {
+	vars, params, err := templateVars(run)
+	if err != nil {
+		return err
+	}
+
+	if err := validateTemplate(run.Task); err != nil {
+		return err
+	}
+
+	rendered := *run.Task
+	if rendered.TaskContent, err = renderTemplate("任务内容", run.Task.TaskContent, contentEscaper(run.Task), vars); err != nil {
+		return fmt.Errorf("渲染任务内容失败: %v", err)
+	}
+	if rendered.TaskParams, err = renderTemplate("任务参数", run.Task.TaskParams, "jsonescape", vars); err != nil {
+		return fmt.Errorf("渲染任务参数失败: %v", err)
+	}
+
+	if len(params) > 0 {
+		data, _ := json.Marshal(params)
+		run.runParams = string(data)
+	}
+	if rendered.TaskContent != run.Task.TaskContent {
+		run.renderedContent = rendered.TaskContent
+	}
+	if rendered.TaskParams != run.Task.TaskParams {
+		run.renderedParams = rendered.TaskParams
+	}
+	run.Task = &rendered
+	return nil
+}